@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/z4x7k/iran-domains-tg-bot/backup"
+)
+
+// backupConfigFromEnv returns the backup configuration, and whether backups are enabled.
+// The backup directory flag, if given, takes precedence over the environment.
+func backupConfigFromEnv(cliCtx *cli.Context) (backup.Config, bool, error) {
+	base := filepath.Base(dbFilename(cliCtx))
+	cfg := backup.Config{
+		Prefix: strings.TrimSuffix(base, filepath.Ext(base)) + "-",
+		Keep:   DefaultBackupKeep,
+	}
+	cfg.Dir, _ = os.LookupEnv(EnvKeyBackupDir)
+	if dir := cliCtx.String(CLIBackupCommandDirFlag); dir != "" {
+		cfg.Dir = dir
+	}
+	if val, ok := os.LookupEnv(EnvKeyBackupKeep); ok && val != "" {
+		keep, err := strconv.Atoi(val)
+		if nil != err || keep < 0 {
+			return backup.Config{}, false, fmt.Errorf("'%s' must be a non-negative integer", EnvKeyBackupKeep)
+		}
+		cfg.Keep = keep
+	}
+	if cliCtx.IsSet(CLIBackupCommandKeepFlag) {
+		cfg.Keep = cliCtx.Int(CLIBackupCommandKeepFlag)
+	}
+
+	return cfg, cfg.Dir != "", nil
+}
+
+func backupIntervalFromEnv() (time.Duration, error) {
+	interval := DefaultBackupInterval
+	if val, ok := os.LookupEnv(EnvKeyBackupInterval); ok && val != "" {
+		parsed, err := time.ParseDuration(val)
+		if nil != err || parsed <= 0 {
+			return 0, fmt.Errorf("'%s' must be a positive duration", EnvKeyBackupInterval)
+		}
+		interval = parsed
+	}
+
+	return interval, nil
+}
+
+func logBackupResult(log zerolog.Logger, res backup.Result) {
+	log.
+		Info().
+		Str("filename", res.Filename).
+		Int64("size", res.Size).
+		Strs("removed", res.Removed).
+		Dur("took", res.Took).
+		Msg("created database backup")
+}
+
+func createBackup(log zerolog.Logger) func(*cli.Context) error {
+	return func(cliCtx *cli.Context) error {
+		ctx, cancel := signal.NotifyContext(cliCtx.Context, os.Interrupt)
+		defer cancel()
+
+		if err := loadEnv(log, cliCtx); nil != err {
+			return err
+		}
+		cfg, ok, err := backupConfigFromEnv(cliCtx)
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		if !ok {
+			return fmt.Errorf("backup: either --%s flag or '%s' environment variable must be set", CLIBackupCommandDirFlag, EnvKeyBackupDir)
+		}
+
+		dbConn, closeDB, err := openDatabase(ctx, log, cliCtx)
+		if nil != err {
+			return err
+		}
+		defer closeDB()
+
+		res, err := backup.Create(ctx, dbConn, cfg)
+		if nil != err {
+			return err
+		}
+		logBackupResult(log, res)
+
+		return nil
+	}
+}
+
+func restoreBackup(log zerolog.Logger) func(*cli.Context) error {
+	return func(cliCtx *cli.Context) error {
+		ctx, cancel := signal.NotifyContext(cliCtx.Context, os.Interrupt)
+		defer cancel()
+
+		from := cliCtx.String(CLIRestoreCommandFromFlag)
+		to := dbFilename(cliCtx)
+		if err := backup.Restore(ctx, from, to); nil != err {
+			return err
+		}
+		log.Info().Str("from", from).Str("db", to).Str("previous", backup.PreviousFilename(to)).Msg("restored database backup")
+
+		return nil
+	}
+}
@@ -0,0 +1,297 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	fileSuffix      = ".db.gz"
+	timestampFormat = "20060102T150405Z"
+	lockSuffix      = ".lock"
+	// restoredSuffix is appended to the name of the database file replaced by Restore, and to its WAL files.
+	restoredSuffix = ".before-restore"
+)
+
+// ErrDatabaseInUse is returned by Restore, and Lock, if another process holds a conflicting lock of the database.
+var ErrDatabaseInUse = errors.New("database is in use by another process, e.g. the bot")
+
+// Lock takes a shared lock of the database, which Restore refuses to run while any process holds.
+// It must be held for as long as the database is open, and released by calling the returned function.
+func Lock(dbFilename string) (func(), error) {
+	unlock, err := lock(dbFilename, false)
+	if nil != err {
+		if errors.Is(err, ErrDatabaseInUse) {
+			return nil, fmt.Errorf("lock: database is being restored: %w", err)
+		}
+		return nil, fmt.Errorf("lock: %v", err)
+	}
+	return unlock, nil
+}
+
+type Config struct {
+	Dir string
+	// Prefix is prepended to backup file names, and is used to find previous backups for rotation.
+	Prefix string
+	// Keep is the number of most recent backups kept by rotation. Zero keeps all of them.
+	Keep int
+}
+
+type Result struct {
+	Filename string
+	Size     int64
+	Removed  []string
+	Took     time.Duration
+}
+
+// Create writes a consistent snapshot of the database using VACUUM INTO, which is safe while the database
+// is in use in WAL mode, compresses it into a timestamped file in the backup directory, and rotates old backups.
+func Create(ctx context.Context, db *sql.DB, cfg Config) (Result, error) {
+	startedAt := time.Now().UTC()
+	if err := os.MkdirAll(cfg.Dir, 0o700); nil != err {
+		return Result{}, fmt.Errorf("backup: failed to create backup directory: %v", err)
+	}
+
+	snapshot, err := os.CreateTemp(cfg.Dir, ".snapshot-*.db")
+	if nil != err {
+		return Result{}, fmt.Errorf("backup: failed to create temporary snapshot file: %v", err)
+	}
+	snapshotPath := snapshot.Name()
+	snapshot.Close()
+	// VACUUM INTO requires the target file to not exist.
+	os.Remove(snapshotPath)
+	defer os.Remove(snapshotPath)
+
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?;", snapshotPath); nil != err {
+		return Result{}, fmt.Errorf("backup: failed to snapshot database: %v", err)
+	}
+
+	filename := filepath.Join(cfg.Dir, cfg.Prefix+startedAt.Format(timestampFormat)+fileSuffix)
+	size, err := compress(snapshotPath, filename)
+	if nil != err {
+		os.Remove(filename)
+		return Result{}, fmt.Errorf("backup: failed to compress snapshot: %v", err)
+	}
+
+	removed, err := Rotate(cfg)
+	if nil != err {
+		return Result{}, err
+	}
+
+	return Result{Filename: filename, Size: size, Removed: removed, Took: time.Since(startedAt)}, nil
+}
+
+// Rotate removes all but the most recent cfg.Keep backups.
+func Rotate(cfg Config) ([]string, error) {
+	if cfg.Keep <= 0 {
+		return nil, nil
+	}
+	backups, err := List(cfg)
+	if nil != err {
+		return nil, err
+	}
+	if len(backups) <= cfg.Keep {
+		return nil, nil
+	}
+
+	removed := backups[:len(backups)-cfg.Keep]
+	for _, name := range removed {
+		if err := os.Remove(name); nil != err {
+			return nil, fmt.Errorf("backup: failed to remove old backup '%s': %v", name, err)
+		}
+	}
+	return removed, nil
+}
+
+// List returns backup file paths sorted from the oldest to the most recent.
+func List(cfg Config) ([]string, error) {
+	entries, err := os.ReadDir(cfg.Dir)
+	if nil != err {
+		return nil, fmt.Errorf("backup: failed to list backup directory: %v", err)
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, cfg.Prefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, cfg.Prefix), fileSuffix)
+		if _, err := time.Parse(timestampFormat, ts); nil != err {
+			continue
+		}
+		backups = append(backups, filepath.Join(cfg.Dir, name))
+	}
+	// Timestamps are fixed width, so lexical order is chronological.
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+// Restore decompresses the backup next to the database file, verifies its integrity,
+// and atomically swaps it in. The replaced database file is kept with a .before-restore suffix,
+// after checkpointing its WAL, whose files, if any remain, are kept with the same suffix.
+// It fails with ErrDatabaseInUse if any process holds the lock of the database, e.g. the bot.
+func Restore(ctx context.Context, backupFilename string, dbFilename string) error {
+	unlock, err := lock(dbFilename, true)
+	if nil != err {
+		return fmt.Errorf("restore: %w", err)
+	}
+	defer unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dbFilename), ".restore-*.db")
+	if nil != err {
+		return fmt.Errorf("restore: failed to create temporary database file: %v", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	if err := decompress(backupFilename, tmpPath); nil != err {
+		return fmt.Errorf("restore: failed to decompress backup: %v", err)
+	}
+	if err := verify(ctx, tmpPath); nil != err {
+		return fmt.Errorf("restore: backup verification failed: %v", err)
+	}
+
+	if _, err := os.Stat(dbFilename); nil == err {
+		// Transactions committed to the WAL, which maintenance doesn't checkpoint automatically, would otherwise
+		// be missing from the kept database file.
+		if err := checkpoint(ctx, dbFilename); nil != err {
+			return fmt.Errorf("restore: failed to checkpoint current database: %v", err)
+		}
+		if err := os.Rename(dbFilename, dbFilename+restoredSuffix); nil != err {
+			return fmt.Errorf("restore: failed to keep current database file: %v", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("restore: failed to stat current database file: %v", err)
+	}
+	// WAL files are named after their database file, so they're kept along with it, and don't apply to the restored one.
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Rename(dbFilename+suffix, dbFilename+restoredSuffix+suffix); nil != err && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("restore: failed to keep '%s' file of current database: %v", suffix, err)
+		}
+	}
+	if err := os.Rename(tmpPath, dbFilename); nil != err {
+		return fmt.Errorf("restore: failed to move restored database in place: %v", err)
+	}
+
+	return nil
+}
+
+// PreviousFilename returns the name the database file replaced by Restore is kept by.
+func PreviousFilename(dbFilename string) string {
+	return dbFilename + restoredSuffix
+}
+
+// Run creates a backup every interval until ctx is done.
+func Run(ctx context.Context, db *sql.DB, cfg Config, interval time.Duration, onResult func(Result, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			onResult(Create(ctx, db, cfg))
+		}
+	}
+}
+
+// checkpoint copies every transaction of the WAL of the database into the database file, and truncates the WAL.
+func checkpoint(ctx context.Context, filename string) error {
+	db, err := sql.Open("sqlite3", "file:"+filename)
+	if nil != err {
+		return err
+	}
+	defer db.Close()
+
+	var busy, logFrames, checkpointed int
+	if err := db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE);").Scan(&busy, &logFrames, &checkpointed); nil != err {
+		return err
+	}
+	if busy != 0 {
+		return errors.New("checkpoint was blocked by another connection")
+	}
+	return nil
+}
+
+func verify(ctx context.Context, filename string) error {
+	db, err := sql.Open("sqlite3", "file:"+filename+"?mode=ro")
+	if nil != err {
+		return err
+	}
+	defer db.Close()
+
+	var res string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check;").Scan(&res); nil != err {
+		return err
+	}
+	if res != "ok" {
+		return fmt.Errorf("database is corrupted: %s", res)
+	}
+	return nil
+}
+
+func compress(src string, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if nil != err {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if nil != err {
+		return 0, err
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); nil != err {
+		return 0, err
+	}
+	if err := zw.Close(); nil != err {
+		return 0, err
+	}
+	if err := out.Sync(); nil != err {
+		return 0, err
+	}
+	info, err := out.Stat()
+	if nil != err {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func decompress(src string, dst string) error {
+	in, err := os.Open(src)
+	if nil != err {
+		return err
+	}
+	defer in.Close()
+
+	zr, err := gzip.NewReader(in)
+	if nil != err {
+		return err
+	}
+	defer zr.Close()
+
+	out, err := os.OpenFile(dst, os.O_TRUNC|os.O_WRONLY, 0o600)
+	if nil != err {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, zr); nil != err {
+		return err
+	}
+	return out.Sync()
+}
@@ -0,0 +1,101 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T, filename string) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filename+"?_journal_mode=WAL")
+	if nil != err {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// The pragma is set per connection.
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func countRows(t *testing.T, filename string) int {
+	t.Helper()
+	conn := openTestDB(t, filename)
+	var n int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM domains").Scan(&n); nil != err {
+		t.Fatalf("failed to count rows of %s: %v", filename, err)
+	}
+	conn.Close()
+	return n
+}
+
+func copyFile(t *testing.T, src string, dst string) {
+	t.Helper()
+	in, err := os.Open(src)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, in); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbFilename := filepath.Join(dir, "domains.db")
+	conn := openTestDB(t, dbFilename)
+	// As maintenance does, so that committed transactions stay in the WAL.
+	for _, stmt := range []string{
+		"PRAGMA wal_autocheckpoint=0",
+		"CREATE TABLE domains (domain TEXT PRIMARY KEY)",
+		"INSERT INTO domains VALUES ('a.ir')",
+	} {
+		if _, err := conn.Exec(stmt); nil != err {
+			t.Fatalf("failed to execute '%s': %v", stmt, err)
+		}
+	}
+	res, err := Create(ctx, conn, Config{Dir: filepath.Join(dir, "backups"), Prefix: "domains-"})
+	if nil != err {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if _, err := conn.Exec("INSERT INTO domains VALUES ('b.ir'), ('c.ir')"); nil != err {
+		t.Fatal(err)
+	}
+	// Closing the last connection checkpoints the WAL, so the files are copied as a crash would leave them.
+	crashed := filepath.Join(dir, "crashed.db")
+	copyFile(t, dbFilename, crashed)
+	copyFile(t, dbFilename+"-wal", crashed+"-wal")
+	conn.Close()
+
+	unlock, err := Lock(crashed)
+	if nil != err {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+	if err := Restore(ctx, res.Filename, crashed); !errors.Is(err, ErrDatabaseInUse) {
+		t.Fatalf("Restore() of a locked database = %v, want %v", err, ErrDatabaseInUse)
+	}
+	unlock()
+
+	if err := Restore(ctx, res.Filename, crashed); nil != err {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if n := countRows(t, crashed); n != 1 {
+		t.Errorf("restored database has %d rows, want 1 of the backup", n)
+	}
+	if n := countRows(t, PreviousFilename(crashed)); n != 3 {
+		t.Errorf("previous database has %d rows, want 3, including the ones committed to its WAL", n)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package backup
+
+// lock is a no-op on platforms without flock, where restoring relies on the bot being stopped.
+func lock(string, bool) (func(), error) {
+	return func() {}, nil
+}
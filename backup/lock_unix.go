@@ -0,0 +1,33 @@
+//go:build unix
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lock takes an advisory lock of the lock file of the database, which is shared by every process using the database,
+// and exclusive while restoring. It fails with ErrDatabaseInUse, rather than waiting, if the lock is taken.
+func lock(dbFilename string, exclusive bool) (func(), error) {
+	f, err := os.OpenFile(dbFilename+lockSuffix, os.O_CREATE|os.O_RDWR, 0o600)
+	if nil != err {
+		return nil, fmt.Errorf("failed to open database lock file: %v", err)
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB); nil != err {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrDatabaseInUse
+		}
+		return nil, fmt.Errorf("failed to lock database lock file: %v", err)
+	}
+
+	// Closing the file releases the lock.
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+)
+
+// command is a bot command, which is both registered as a message handler, and listed in the command menu,
+// so that the two are always in sync.
+type command struct {
+	// name is the command without the leading slash, e.g. "help".
+	name string
+	// args is whether the command takes space separated arguments, e.g. /tag <domain> <tag>.
+	args    bool
+	handler bot.HandlerFunc
+	// description is the menu description message, or empty if the command isn't listed, e.g. /start.
+	description i18n.MessageID
+	// admin is whether the command is only listed in the menus of the admins.
+	admin bool
+	// group is whether the command is only handled in groups, in which it may be addressed to the bot, e.g. /add@bot.
+	group bool
+}
+
+func (h *Handler) commands() []command {
+	commands := []command{
+		{name: "start", handler: h.handleStartCommand},
+		{name: "info", handler: h.handleInfoCommand, description: i18n.MsgCommandInfo},
+		{name: "help", handler: h.handleHelpCommand, description: i18n.MsgCommandHelp},
+		{name: "language", handler: h.handleLanguageCommand, description: i18n.MsgCommandLanguage},
+		{name: "mine", handler: h.handleMineCommand, description: i18n.MsgCommandMine},
+		{name: "stats", handler: h.handleStatsCommand, description: i18n.MsgCommandStats},
+		{name: "public_name", handler: h.handlePublicNameCommand, description: i18n.MsgCommandPublicName},
+		{name: "tag", args: true, handler: h.handleTagCommand, description: i18n.MsgCommandTag, admin: true},
+		{name: "untag", args: true, handler: h.handleUntagCommand, description: i18n.MsgCommandUntag, admin: true},
+		{name: "tags", handler: h.handleTagsCommand, description: i18n.MsgCommandTags, admin: true},
+	}
+	if h.groupsEnabled {
+		commands = append(commands,
+			command{name: "add", args: true, handler: h.handleAddCommand, description: i18n.MsgCommandAdd, group: true},
+			command{name: "silent", handler: h.handleSilentCommand, description: i18n.MsgCommandSilent, group: true},
+			command{name: "admins_only", handler: h.handleAdminsOnlyCommand, description: i18n.MsgCommandAdminsOnly, group: true},
+		)
+	}
+	return commands
+}
+
+func registerCommands(b *bot.Bot, commands []command, botUsername string) {
+	for _, c := range commands {
+		if c.group {
+			b.RegisterHandlerMatchFunc(matchGroupCommand(c.name, botUsername), c.handler)
+		} else if c.args {
+			b.RegisterHandlerMatchFunc(matchArgsCommand(c.name), c.handler)
+		} else {
+			b.RegisterHandler(bot.HandlerTypeMessageText, "/"+c.name, bot.MatchTypeExact, c.handler)
+		}
+	}
+}
+
+// matchArgsCommand matches the command either alone, or followed by whitespace and its arguments,
+// so that the handler replies to a bare command with its usage, instead of it being taken for a submission.
+func matchArgsCommand(name string) bot.MatchFunc {
+	return func(update *models.Update) bool {
+		if nil == update.Message {
+			return false
+		}
+		rest, ok := strings.CutPrefix(update.Message.Text, "/"+name)
+		if !ok {
+			return false
+		}
+		r, _ := utf8.DecodeRuneInString(rest)
+		return rest == "" || unicode.IsSpace(r)
+	}
+}
+
+// menu returns the command menu of either private chats, or groups, in the language, including the admin commands if admin is true.
+func (h *Handler) menu(lang i18n.Lang, commands []command, group, admin bool) []models.BotCommand {
+	var res []models.BotCommand
+	for _, c := range commands {
+		if c.description == "" || c.group != group || (c.admin && !admin) {
+			continue
+		}
+		res = append(res, models.BotCommand{Command: c.name, Description: h.plainText(lang, c.description, nil)})
+	}
+	return res
+}
+
+// publishCommands sets the command menus, and the descriptions of the bot, in every language of the catalog.
+// The menus of admins, which include the admin commands, are set in the scope of their private chats with the bot,
+// and groups have a menu of the group commands in group mode.
+// Users whose language isn't in the catalog get the ones of the default language.
+// Failures are logged, as the bot still works without the menu.
+func (h *Handler) publishCommands(ctx context.Context, b *bot.Bot, commands []command) {
+	admins := make([]int64, 0, len(h.admins))
+	for id := range h.admins {
+		admins = append(admins, id)
+	}
+	sort.Slice(admins, func(i, j int) bool { return admins[i] < admins[j] })
+
+	// An empty language code applies to all languages without their own menu, and descriptions.
+	langs := append([]i18n.Lang{""}, h.catalog.Languages()...)
+	for _, lang := range langs {
+		textLang := lang
+		if lang == "" {
+			textLang = h.defaultLang
+		}
+		log := h.log.With().Str("lang", string(lang)).Logger()
+
+		if _, err := b.SetMyCommands(ctx, &bot.SetMyCommandsParams{
+			Commands:     h.menu(textLang, commands, false, false),
+			Scope:        &models.BotCommandScopeDefault{},
+			LanguageCode: string(lang),
+		}); nil != err {
+			log.Error().Err(err).Msg("failed to set bot commands")
+		}
+		if groupMenu := h.menu(textLang, commands, true, false); len(groupMenu) > 0 {
+			if _, err := b.SetMyCommands(ctx, &bot.SetMyCommandsParams{
+				Commands:     groupMenu,
+				Scope:        &models.BotCommandScopeAllGroupChats{},
+				LanguageCode: string(lang),
+			}); nil != err {
+				log.Error().Err(err).Msg("failed to set group bot commands")
+			}
+		} else if _, err := b.DeleteMyCommands(ctx, &bot.DeleteMyCommandsParams{
+			Scope:        &models.BotCommandScopeAllGroupChats{},
+			LanguageCode: string(lang),
+		}); nil != err {
+			// Groups get the menu of the default scope, once group mode is turned off.
+			log.Error().Err(err).Msg("failed to delete group bot commands")
+		}
+		adminMenu := h.menu(textLang, commands, false, true)
+		for _, id := range admins {
+			if _, err := b.SetMyCommands(ctx, &bot.SetMyCommandsParams{
+				Commands:     adminMenu,
+				Scope:        &models.BotCommandScopeChat{ChatID: id},
+				LanguageCode: string(lang),
+			}); nil != err {
+				// Telegram doesn't know the private chat of admins who have never started the bot.
+				log.Error().Err(err).Dict("scope_chat", h.replyDict(id)).Msg("failed to set admin bot commands")
+			}
+		}
+
+		if _, err := b.SetMyDescription(ctx, &bot.SetMyDescriptionParams{
+			Description:  h.plainText(textLang, i18n.MsgDescription, nil),
+			LanguageCode: string(lang),
+		}); nil != err {
+			log.Error().Err(err).Msg("failed to set bot description")
+		}
+		if _, err := b.SetMyShortDescription(ctx, &bot.SetMyShortDescriptionParams{
+			ShortDescription: h.plainText(textLang, i18n.MsgShortDescription, nil),
+			LanguageCode:     string(lang),
+		}); nil != err {
+			log.Error().Err(err).Msg("failed to set bot short description")
+		}
+	}
+	h.log.Info().Int("languages", len(langs)-1).Int("admins", len(admins)).Msg("published bot commands, and descriptions")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestMatchArgsCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "/tag", want: true},
+		{text: "/tag git.ir news", want: true},
+		{text: "/tag\ngit.ir news", want: true},
+		{text: "/tags", want: false},
+		{text: "/tagged git.ir", want: false},
+		{text: "git.ir", want: false},
+	}
+	match := matchArgsCommand("tag")
+	for _, tt := range tests {
+		if got := match(&models.Update{Message: &models.Message{Text: tt.text}}); got != tt.want {
+			t.Errorf("matchArgsCommand(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+	if match(&models.Update{}) {
+		t.Errorf("matchArgsCommand matched an update without a message")
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// ChatSettings returns the settings of the group chat, which are all off if they were never changed.
+func ChatSettings(ctx context.Context, db *sql.DB, chatID pseudonym.ID) (model.Chats, error) {
+	var dest model.Chats
+	err := table.Chats.
+		SELECT(table.Chats.AllColumns).
+		WHERE(table.Chats.TheChatID.EQ(sqlite.Int64(int64(chatID)))).
+		QueryContext(ctx, db, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return model.Chats{TheChatID: int64(chatID)}, nil
+		}
+		return model.Chats{}, WrapErr(err, "failed to query chat settings")
+	}
+
+	return dest, nil
+}
+
+func SetChatSettings(ctx context.Context, db *sql.DB, settings model.Chats) error {
+	settings.UpdatedTs = time.Now().UTC().Unix()
+	stmt := table.Chats.
+		INSERT(table.Chats.AllColumns).
+		MODEL(settings).
+		ON_CONFLICT(table.Chats.TheChatID).
+		DO_UPDATE(sqlite.SET(
+			table.Chats.Silent.SET(table.Chats.EXCLUDED.Silent),
+			table.Chats.AdminsOnly.SET(table.Chats.EXCLUDED.AdminsOnly),
+			table.Chats.UpdatedTs.SET(table.Chats.EXCLUDED.UpdatedTs),
+		))
+	err := Retry(ctx, func() error {
+		_, err := stmt.ExecContext(ctx, db)
+		return err
+	})
+	if nil != err {
+		return WrapErr(err, "failed to update chat settings")
+	}
+
+	return nil
+}
@@ -4,39 +4,59 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"time"
 
-	"github.com/mattn/go-sqlite3"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
 
 	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
 	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
 )
 
 var (
-	ErrDuplicateDomain = errors.New("domain already exists")
-	ErrBusy            = errors.New("database is busy at the moment. try again later")
+	ErrBusy           = errors.New("database is busy at the moment. try again later")
+	ErrDomainNotFound = errors.New("domain does not exist")
 )
 
-func InsertDomain(ctx context.Context, db *sql.DB, domain string, userID int64) error {
-	now := time.Now().UTC().Unix()
-	res, err := table.Domains.
-		INSERT(table.Domains.AllColumns).
-		MODEL(model.Domains{Domain: domain, CreatedTs: now, CreatedByID: userID}).
-		ExecContext(ctx, db)
+func FindDomain(ctx context.Context, db *sql.DB, domain string) (*model.Domains, error) {
+	var dest model.Domains
+	err := table.Domains.
+		SELECT(table.Domains.AllColumns).
+		WHERE(table.Domains.Domain.EQ(sqlite.String(domain))).
+		LIMIT(1).
+		QueryContext(ctx, db, &dest)
 	if nil != err {
-		var sqlErr sqlite3.Error
-		if errors.As(err, &sqlErr) {
-
-			if sqlErr.Code == sqlite3.ErrConstraint && sqlErr.Error() == "UNIQUE constraint failed: domains.domain" {
-				return ErrDuplicateDomain
-			}
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrDomainNotFound
 		}
-		return fmt.Errorf("db: failed to insert domain into database: %v", err)
-	} else if affectedRows, err := res.RowsAffected(); nil != err {
-		return fmt.Errorf("db: failed to get number of affected rows by domain insert query: %v", err)
-	} else if affectedRows != 1 {
-		return fmt.Errorf("expected 1 row to be affected by domain insert query, got %d", affectedRows)
+		return nil, WrapErr(err, "failed to query domain from database")
+	}
+
+	return &dest, nil
+}
+
+// ListDomains returns the domains created before the given time, ordered by name.
+func ListDomains(ctx context.Context, db *sql.DB, before time.Time) ([]model.Domains, error) {
+	var dest []model.Domains
+	err := table.Domains.
+		SELECT(table.Domains.AllColumns).
+		WHERE(table.Domains.CreatedTs.LT(sqlite.Int64(before.Unix()))).
+		ORDER_BY(table.Domains.Domain.ASC()).
+		QueryContext(ctx, db, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, WrapErr(err, "failed to list domains")
+	}
+
+	return dest, nil
+}
+
+// Ping reads the domains table, rather than only checking the connection, so that a locked database fails once
+// the busy timeout expires.
+func Ping(ctx context.Context, db *sql.DB) error {
+	query, args := table.Domains.SELECT(table.Domains.Domain).LIMIT(1).Sql()
+	var domain string
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&domain); nil != err && !errors.Is(err, sql.ErrNoRows) {
+		return WrapErr(err, "failed to ping database")
 	}
 
 	return nil
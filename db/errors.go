@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	ErrConstraintUnique = errors.New("unique constraint failed")
+	ErrLocked           = errors.New("database table is locked")
+	ErrFull             = errors.New("database or disk is full")
+	ErrCorrupt          = errors.New("database disk image is malformed")
+)
+
+const (
+	// MaxBusyRetries is the number of times Retry runs a write again after the database is busy.
+	MaxBusyRetries   = 4
+	busyRetryBackoff = 25 * time.Millisecond
+)
+
+// Classify maps sqlite errors to ErrConstraintUnique, ErrBusy, ErrLocked, ErrFull, or ErrCorrupt
+// by their result codes, and returns nil for any other error. Errors already wrapped by WrapErr keep their class.
+func Classify(err error) error {
+	for _, kind := range []error{ErrConstraintUnique, ErrBusy, ErrLocked, ErrFull, ErrCorrupt} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	var sqlErr sqlite3.Error
+	if !errors.As(err, &sqlErr) {
+		return nil
+	}
+	switch sqlErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return ErrConstraintUnique
+	}
+	switch sqlErr.Code {
+	case sqlite3.ErrBusy:
+		return ErrBusy
+	case sqlite3.ErrLocked:
+		return ErrLocked
+	case sqlite3.ErrFull:
+		return ErrFull
+	case sqlite3.ErrCorrupt, sqlite3.ErrNotADB:
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// WrapErr prefixes err with msg. Classified errors match their sentinel error with errors.Is.
+func WrapErr(err error, msg string) error {
+	if kind := Classify(err); nil != kind {
+		return fmt.Errorf("db: %s: %w: %v", msg, kind, err)
+	}
+	return fmt.Errorf("db: %s: %v", msg, err)
+}
+
+// Retry runs the write fn again with an increasing delay while it fails because the database is busy or locked,
+// which the busy timeout doesn't prevent when a read transaction is upgraded to a write one on a stale WAL snapshot.
+func Retry(ctx context.Context, fn func() error) error {
+	backoff := busyRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if nil == err || attempt >= MaxBusyRetries {
+			return err
+		}
+		if kind := Classify(err); kind != ErrBusy && kind != ErrLocked {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestClassify(t *testing.T) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT UNIQUE)"); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := conn.Exec("INSERT INTO t (id, name) VALUES (1, 'a')"); nil != err {
+		t.Fatal(err)
+	}
+	_, uniqueErr := conn.Exec("INSERT INTO t (id, name) VALUES (2, 'a')")
+	_, primaryKeyErr := conn.Exec("INSERT INTO t (id, name) VALUES (1, 'b')")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "unique", err: uniqueErr, want: ErrConstraintUnique},
+		{name: "primary key", err: primaryKeyErr, want: ErrConstraintUnique},
+		{name: "other constraint", err: sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintNotNull}},
+		{name: "busy", err: sqlite3.Error{Code: sqlite3.ErrBusy}, want: ErrBusy},
+		{name: "locked", err: sqlite3.Error{Code: sqlite3.ErrLocked}, want: ErrLocked},
+		{name: "full", err: sqlite3.Error{Code: sqlite3.ErrFull}, want: ErrFull},
+		{name: "not a database", err: sqlite3.Error{Code: sqlite3.ErrNotADB}, want: ErrCorrupt},
+		{name: "wrapped by fmt", err: fmt.Errorf("query: %w", sqlite3.Error{Code: sqlite3.ErrBusy}), want: ErrBusy},
+		{name: "wrapped by WrapErr", err: WrapErr(sqlite3.Error{Code: sqlite3.ErrLocked}, "failed to insert"), want: ErrLocked},
+		{name: "other", err: errors.New("connection refused")},
+		{name: "nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+
+	t.Run("until success", func(t *testing.T) {
+		calls := 0
+		err := Retry(ctx, func() error {
+			calls++
+			if calls < 3 {
+				return busy
+			}
+			return nil
+		})
+		if nil != err || calls != 3 {
+			t.Errorf("Retry() = %v after %d calls, want success after 3", err, calls)
+		}
+	})
+
+	t.Run("up to the max retries", func(t *testing.T) {
+		calls := 0
+		err := Retry(ctx, func() error {
+			calls++
+			return sqlite3.Error{Code: sqlite3.ErrLocked}
+		})
+		if Classify(err) != ErrLocked || calls != MaxBusyRetries+1 {
+			t.Errorf("Retry() = %v after %d calls, want locked after %d", err, calls, MaxBusyRetries+1)
+		}
+	})
+
+	t.Run("not other errors", func(t *testing.T) {
+		calls := 0
+		want := sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}
+		err := Retry(ctx, func() error {
+			calls++
+			return want
+		})
+		if !errors.Is(err, want) || calls != 1 {
+			t.Errorf("Retry() = %v after %d calls, want %v after 1", err, calls, want)
+		}
+	})
+
+	t.Run("until ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		calls := 0
+		startedAt := time.Now()
+		err := Retry(ctx, func() error {
+			calls++
+			cancel()
+			return busy
+		})
+		if !errors.Is(err, busy) || calls != 1 {
+			t.Errorf("Retry() = %v after %d calls, want the busy error after 1", err, calls)
+		}
+		if took := time.Since(startedAt); took >= busyRetryBackoff {
+			t.Errorf("Retry() took %v after ctx was canceled, want it to return without waiting", took)
+		}
+	})
+}
@@ -10,5 +10,5 @@ package model
 type Domains struct {
 	Domain      string `sql:"primary_key"`
 	CreatedTs   int64
-	CreatedByID int64
+	CreatedByID *int64
 }
@@ -8,7 +8,8 @@
 package model
 
 type UsersRateLimit struct {
-	TheUserID    int64 `sql:"primary_key"`
-	LastAccessTs int64
-	TheCount     int64
+	TheUserID            int64  `sql:"primary_key"`
+	Outcome              string `sql:"primary_key"`
+	WindowSeconds        int64  `sql:"primary_key"`
+	TheoreticalArrivalMs int64
 }
@@ -10,7 +10,15 @@ package table
 // UseSchema sets a new schema name for all generated table SQL builder types. It is recommended to invoke
 // this method only once at the beginning of the program.
 func UseSchema(schema string) {
+	Chats = Chats.FromSchema(schema)
+	DomainTags = DomainTags.FromSchema(schema)
 	Domains = Domains.FromSchema(schema)
+	Hosts = Hosts.FromSchema(schema)
 	Migrations = Migrations.FromSchema(schema)
+	Publications = Publications.FromSchema(schema)
+	PurgeRuns = PurgeRuns.FromSchema(schema)
+	Tags = Tags.FromSchema(schema)
+	UsersLanguage = UsersLanguage.FromSchema(schema)
+	UsersPublicName = UsersPublicName.FromSchema(schema)
 	UsersRateLimit = UsersRateLimit.FromSchema(schema)
 }
@@ -17,9 +17,10 @@ type usersRateLimitTable struct {
 	sqlite.Table
 
 	// Columns
-	TheUserID    sqlite.ColumnInteger
-	LastAccessTs sqlite.ColumnInteger
-	TheCount     sqlite.ColumnInteger
+	TheUserID            sqlite.ColumnInteger
+	Outcome              sqlite.ColumnString
+	WindowSeconds        sqlite.ColumnInteger
+	TheoreticalArrivalMs sqlite.ColumnInteger
 
 	AllColumns     sqlite.ColumnList
 	MutableColumns sqlite.ColumnList
@@ -60,20 +61,22 @@ func newUsersRateLimitTable(schemaName, tableName, alias string) *UsersRateLimit
 
 func newUsersRateLimitTableImpl(schemaName, tableName, alias string) usersRateLimitTable {
 	var (
-		TheUserIDColumn    = sqlite.IntegerColumn("the_user_id")
-		LastAccessTsColumn = sqlite.IntegerColumn("last_access_ts")
-		TheCountColumn     = sqlite.IntegerColumn("the_count")
-		allColumns         = sqlite.ColumnList{TheUserIDColumn, LastAccessTsColumn, TheCountColumn}
-		mutableColumns     = sqlite.ColumnList{LastAccessTsColumn, TheCountColumn}
+		TheUserIDColumn            = sqlite.IntegerColumn("the_user_id")
+		OutcomeColumn              = sqlite.StringColumn("outcome")
+		WindowSecondsColumn        = sqlite.IntegerColumn("window_seconds")
+		TheoreticalArrivalMsColumn = sqlite.IntegerColumn("theoretical_arrival_ms")
+		allColumns                 = sqlite.ColumnList{TheUserIDColumn, OutcomeColumn, WindowSecondsColumn, TheoreticalArrivalMsColumn}
+		mutableColumns             = sqlite.ColumnList{TheoreticalArrivalMsColumn}
 	)
 
 	return usersRateLimitTable{
 		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		TheUserID:    TheUserIDColumn,
-		LastAccessTs: LastAccessTsColumn,
-		TheCount:     TheCountColumn,
+		TheUserID:            TheUserIDColumn,
+		Outcome:              OutcomeColumn,
+		WindowSeconds:        WindowSecondsColumn,
+		TheoreticalArrivalMs: TheoreticalArrivalMsColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+var (
+	ErrDuplicateHost = errors.New("host already exists")
+	ErrHostNotFound  = errors.New("host does not exist")
+)
+
+func FindHost(ctx context.Context, db *sql.DB, host string) (*model.Hosts, error) {
+	var dest model.Hosts
+	err := table.Hosts.
+		SELECT(table.Hosts.AllColumns).
+		WHERE(table.Hosts.Host.EQ(sqlite.String(host))).
+		LIMIT(1).
+		QueryContext(ctx, db, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, ErrHostNotFound
+		}
+		return nil, WrapErr(err, "failed to query host from database")
+	}
+
+	return &dest, nil
+}
+
+// InsertHost inserts the host, and its domain if it isn't listed yet, with the submitter recorded as the submitter of the host,
+// and of the domain if it's inserted. It reports whether the domain was inserted.
+func InsertHost(ctx context.Context, db *sql.DB, host, domain string, submitter pseudonym.ID) (bool, error) {
+	var domainInserted bool
+	err := Retry(ctx, func() (err error) {
+		domainInserted, err = insertHost(ctx, db, host, domain, submitter)
+		return err
+	})
+	if nil != err {
+		// The host is the only unique column of the table, and domain inserts never conflict.
+		if Classify(err) == ErrConstraintUnique {
+			return false, ErrDuplicateHost
+		}
+		return false, WrapErr(err, "failed to insert host into database")
+	}
+
+	return domainInserted, nil
+}
+
+func insertHost(ctx context.Context, db *sql.DB, host, domain string, submitter pseudonym.ID) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Unix()
+	createdByID := int64(submitter)
+	res, err := table.Domains.
+		INSERT(table.Domains.AllColumns).
+		MODEL(model.Domains{Domain: domain, CreatedTs: now, CreatedByID: &createdByID}).
+		ON_CONFLICT(table.Domains.Domain).
+		DO_NOTHING().
+		ExecContext(ctx, tx)
+	if nil != err {
+		return false, err
+	}
+	affectedRows, err := res.RowsAffected()
+	if nil != err {
+		return false, fmt.Errorf("failed to get number of affected rows by domain insert query: %v", err)
+	}
+
+	_, err = table.Hosts.
+		INSERT(table.Hosts.AllColumns).
+		MODEL(model.Hosts{Host: host, Domain: domain, CreatedTs: now, CreatedByID: &createdByID}).
+		ExecContext(ctx, tx)
+	if nil != err {
+		return false, err
+	}
+
+	return affectedRows == 1, tx.Commit()
+}
+
+// ListHosts returns the hosts created before the given time, ordered by name.
+func ListHosts(ctx context.Context, db *sql.DB, before time.Time) ([]model.Hosts, error) {
+	var dest []model.Hosts
+	err := table.Hosts.
+		SELECT(table.Hosts.AllColumns).
+		WHERE(table.Hosts.CreatedTs.LT(sqlite.Int64(before.Unix()))).
+		ORDER_BY(table.Hosts.Host.ASC()).
+		QueryContext(ctx, db, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, WrapErr(err, "failed to list hosts")
+	}
+
+	return dest, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// UserLanguage returns the language preferred by the user, or an empty string if they have no preference.
+func UserLanguage(ctx context.Context, db *sql.DB, userID pseudonym.ID) (string, error) {
+	var dest model.UsersLanguage
+	err := table.UsersLanguage.
+		SELECT(table.UsersLanguage.AllColumns).
+		WHERE(table.UsersLanguage.TheUserID.EQ(sqlite.Int64(int64(userID)))).
+		QueryContext(ctx, db, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return "", nil
+		}
+		return "", WrapErr(err, "failed to query user language")
+	}
+
+	return dest.Language, nil
+}
+
+func SetUserLanguage(ctx context.Context, db *sql.DB, userID pseudonym.ID, lang string) error {
+	stmt := table.UsersLanguage.
+		INSERT(table.UsersLanguage.AllColumns).
+		MODEL(model.UsersLanguage{TheUserID: int64(userID), Language: lang, UpdatedTs: time.Now().UTC().Unix()}).
+		ON_CONFLICT(table.UsersLanguage.TheUserID).
+		DO_UPDATE(sqlite.SET(
+			table.UsersLanguage.Language.SET(table.UsersLanguage.EXCLUDED.Language),
+			table.UsersLanguage.UpdatedTs.SET(table.UsersLanguage.EXCLUDED.UpdatedTs),
+		))
+	err := Retry(ctx, func() error {
+		_, err := stmt.ExecContext(ctx, db)
+		return err
+	})
+	if nil != err {
+		return WrapErr(err, "failed to update user language")
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+var (
+	pseudonymizer *pseudonym.Pseudonymizer
+	dialect       string
+)
+
+// SetPseudonymizer sets the key used to rewrite raw Telegram identifiers stored
+// before pseudonymization. It must be called before running migrations.
+func SetPseudonymizer(p *pseudonym.Pseudonymizer) {
+	pseudonymizer = p
+}
+
+// SetDialect sets the goose dialect of the database being migrated. Goose registers Go migrations for every dialect,
+// while the ones of this package only apply to sqlite, and are skipped for others. It must be called before running migrations.
+func SetDialect(d string) {
+	dialect = d
+}
+
+func init() {
+	goose.AddMigrationContext(upPseudonymizeSubmitters, downPseudonymizeSubmitters)
+}
+
+// downPseudonymizeSubmitters fails, as pseudonyms can't be reverted to the raw identifiers, and running the up migration
+// again would pseudonymize the pseudonyms, unlinking every submitter from their domains.
+func downPseudonymizeSubmitters(context.Context, *sql.Tx) error {
+	if dialect != "sqlite3" {
+		return nil
+	}
+	return errors.New("pseudonymization of submitters is irreversible")
+}
+
+func upPseudonymizeSubmitters(ctx context.Context, tx *sql.Tx) error {
+	if dialect == "" {
+		return errors.New("dialect must be set to run the pseudonymization migration")
+	}
+	// Other databases store pseudonyms from their first migration.
+	if dialect != "sqlite3" {
+		return nil
+	}
+	if pseudonymizer == nil {
+		return errors.New("pseudonymizer must be set to rewrite raw user identifiers")
+	}
+
+	if err := rewriteIDs(ctx, tx, "SELECT domain, created_by_id FROM domains", "UPDATE domains SET created_by_id = ? WHERE domain = ?"); nil != err {
+		return fmt.Errorf("failed to pseudonymize domains submitters: %v", err)
+	}
+	if err := rewriteIDs(ctx, tx, "SELECT DISTINCT the_user_id, the_user_id FROM users_rate_limit", "UPDATE users_rate_limit SET the_user_id = ? WHERE the_user_id = ?"); nil != err {
+		return fmt.Errorf("failed to pseudonymize rate limited users: %v", err)
+	}
+
+	return nil
+}
+
+// rewriteIDs reads (key, id) pairs using selectQuery, and calls updateQuery with (pseudonym, key) for each.
+func rewriteIDs(ctx context.Context, tx *sql.Tx, selectQuery string, updateQuery string) error {
+	rows, err := tx.QueryContext(ctx, selectQuery)
+	if nil != err {
+		return err
+	}
+	type pair struct {
+		key any
+		id  int64
+	}
+	var pairs []pair
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.key, &p.id); nil != err {
+			rows.Close()
+			return err
+		}
+		pairs = append(pairs, p)
+	}
+	if err := rows.Close(); nil != err {
+		return err
+	}
+	if err := rows.Err(); nil != err {
+		return err
+	}
+
+	for _, p := range pairs {
+		if _, err := tx.ExecContext(ctx, updateQuery, int64(pseudonymizer.ID(p.id)), p.key); nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/dbtest"
+	"github.com/z4x7k/iran-domains-tg-bot/db/migration"
+)
+
+func TestPseudonymizeSubmittersIsIrreversible(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+
+	if err := goose.DownToContext(ctx, conn, "scripts", 20231021100000-1); nil == err {
+		t.Fatal("migrating down past the pseudonymization succeeded, want error")
+	}
+	version, err := goose.GetDBVersionContext(ctx, conn)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if version != 20231021100000 {
+		t.Errorf("version after failed down migration = %d, want the pseudonymization to stay applied", version)
+	}
+}
+
+func TestPseudonymizeSubmittersOnlyAppliesToSQLite(t *testing.T) {
+	tests := []struct {
+		dialect string
+		want    int64
+	}{
+		{dialect: "sqlite3", want: int64(dbtest.Pseudonymizer.ID(1))},
+		{dialect: "postgres", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			ctx := context.Background()
+			conn, err := sql.Open("sqlite3", ":memory:")
+			if nil != err {
+				t.Fatal(err)
+			}
+			conn.SetMaxOpenConns(1)
+			defer conn.Close()
+
+			goose.SetBaseFS(migration.FS)
+			if err := goose.SetDialect("sqlite3"); nil != err {
+				t.Fatal(err)
+			}
+			migration.SetPseudonymizer(dbtest.Pseudonymizer)
+			migration.SetDialect("sqlite3")
+			if err := goose.UpToContext(ctx, conn, "scripts", 20231021100000-1); nil != err {
+				t.Fatal(err)
+			}
+			if _, err := conn.ExecContext(ctx, "INSERT INTO domains (domain, created_ts, created_by_id) VALUES ('git.ir', 0, 1)"); nil != err {
+				t.Fatal(err)
+			}
+
+			// The dialect only tells the migration which database it runs against, which is sqlite either way.
+			migration.SetDialect(tt.dialect)
+			defer migration.SetDialect("sqlite3")
+			if err := goose.UpToContext(ctx, conn, "scripts", 20231021100000); nil != err {
+				t.Fatal(err)
+			}
+			var got int64
+			if err := conn.QueryRowContext(ctx, "SELECT created_by_id FROM domains").Scan(&got); nil != err {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("created_by_id = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+-- +goose Up
+DROP TABLE users_rate_limit;
+CREATE TABLE users_rate_limit (
+	the_user_id BIGINT NOT NULL,
+	outcome TEXT NOT NULL,
+	window_seconds BIGINT NOT NULL,
+	theoretical_arrival_ms BIGINT NOT NULL,
+	PRIMARY KEY (the_user_id, outcome, window_seconds)
+);
+
+-- +goose Down
+DROP TABLE users_rate_limit;
+CREATE TABLE users_rate_limit (
+	the_user_id BIGINT NOT NULL,
+	outcome TEXT NOT NULL,
+	window_seconds BIGINT NOT NULL,
+	window_start_ts BIGINT NOT NULL,
+	the_count BIGINT NOT NULL,
+	PRIMARY KEY (the_user_id, outcome, window_seconds)
+);
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE domains_new (
+	domain TEXT NOT NULL PRIMARY KEY,
+	created_ts BIGINT NOT NULL,
+	created_by_id BIGINT
+);
+INSERT INTO domains_new (domain, created_ts, created_by_id) SELECT domain, created_ts, created_by_id FROM domains;
+DROP TABLE domains;
+ALTER TABLE domains_new RENAME TO domains;
+
+CREATE TABLE purge_runs (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	started_ts BIGINT NOT NULL,
+	finished_ts BIGINT NOT NULL,
+	rate_limit_rows_deleted BIGINT NOT NULL,
+	domains_unlinked BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE purge_runs;
+
+CREATE TABLE domains_old (
+	domain TEXT NOT NULL PRIMARY KEY,
+	created_ts BIGINT NOT NULL,
+	created_by_id BIGINT NOT NULL
+);
+INSERT INTO domains_old (domain, created_ts, created_by_id) SELECT domain, created_ts, COALESCE(created_by_id, 0) FROM domains;
+DROP TABLE domains;
+ALTER TABLE domains_old RENAME TO domains;
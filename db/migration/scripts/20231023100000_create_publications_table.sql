@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE publications (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	published_ts BIGINT NOT NULL,
+	domains_count BIGINT NOT NULL,
+	new_domains_count BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE publications;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE tags (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	created_ts BIGINT NOT NULL
+);
+
+CREATE TABLE domain_tags (
+	domain TEXT NOT NULL REFERENCES domains (domain) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	created_ts BIGINT NOT NULL,
+	PRIMARY KEY (domain, tag_id)
+);
+CREATE INDEX domain_tags_tag_id_idx ON domain_tags (tag_id);
+
+INSERT INTO tags (name, created_ts) VALUES
+	('bank', CAST(strftime('%s', 'now') AS BIGINT)),
+	('cdn', CAST(strftime('%s', 'now') AS BIGINT)),
+	('ecommerce', CAST(strftime('%s', 'now') AS BIGINT)),
+	('government', CAST(strftime('%s', 'now') AS BIGINT)),
+	('streaming', CAST(strftime('%s', 'now') AS BIGINT));
+
+-- +goose Down
+DROP TABLE domain_tags;
+DROP TABLE tags;
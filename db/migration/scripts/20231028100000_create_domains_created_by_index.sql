@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX domains_created_by_id_idx ON domains (created_by_id, created_ts);
+
+-- +goose Down
+DROP INDEX domains_created_by_id_idx;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE users_public_name (
+	the_user_id BIGINT NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	updated_ts BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE users_public_name;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE hosts ADD COLUMN created_by_id BIGINT;
+-- Hosts inserted along with their domain share its creation time, and submitter. The submitters of the other hosts
+-- weren't recorded.
+UPDATE hosts SET created_by_id = (
+	SELECT domains.created_by_id FROM domains WHERE domains.domain = hosts.domain AND domains.created_ts = hosts.created_ts
+);
+CREATE INDEX hosts_created_by_id_idx ON hosts (created_by_id, created_ts);
+
+ALTER TABLE purge_runs ADD COLUMN hosts_unlinked BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE purge_runs DROP COLUMN hosts_unlinked;
+DROP INDEX hosts_created_by_id_idx;
+ALTER TABLE hosts DROP COLUMN created_by_id;
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	pgmodel "github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+func ChatSettings(ctx context.Context, dbConn *sql.DB, chatID pseudonym.ID) (model.Chats, error) {
+	var dest pgmodel.Chats
+	err := table.Chats.
+		SELECT(table.Chats.AllColumns).
+		WHERE(table.Chats.TheChatID.EQ(postgres.Int64(int64(chatID)))).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return model.Chats{TheChatID: int64(chatID)}, nil
+		}
+		if IsBusy(err) {
+			return model.Chats{}, db.ErrBusy
+		}
+		return model.Chats{}, fmt.Errorf("db: failed to query chat settings: %v", err)
+	}
+
+	return model.Chats(dest), nil
+}
+
+func SetChatSettings(ctx context.Context, dbConn *sql.DB, settings model.Chats) error {
+	settings.UpdatedTs = time.Now().UTC().Unix()
+	_, err := table.Chats.
+		INSERT(table.Chats.AllColumns).
+		MODEL(pgmodel.Chats(settings)).
+		ON_CONFLICT(table.Chats.TheChatID).
+		DO_UPDATE(postgres.SET(
+			table.Chats.Silent.SET(table.Chats.EXCLUDED.Silent),
+			table.Chats.AdminsOnly.SET(table.Chats.EXCLUDED.AdminsOnly),
+			table.Chats.UpdatedTs.SET(table.Chats.EXCLUDED.UpdatedTs),
+		)).
+		ExecContext(ctx, dbConn)
+	if nil != err {
+		if IsBusy(err) {
+			return db.ErrBusy
+		}
+		return fmt.Errorf("db: failed to update chat settings: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	pgmodel "github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+func FindHost(ctx context.Context, dbConn *sql.DB, host string) (*model.Hosts, error) {
+	var dest pgmodel.Hosts
+	err := table.Hosts.
+		SELECT(table.Hosts.AllColumns).
+		WHERE(table.Hosts.Host.EQ(postgres.String(host))).
+		LIMIT(1).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, db.ErrHostNotFound
+		}
+		if IsBusy(err) {
+			return nil, db.ErrBusy
+		}
+		return nil, fmt.Errorf("db: failed to query host from database: %v", err)
+	}
+
+	res := model.Hosts(dest)
+	return &res, nil
+}
+
+func InsertHost(ctx context.Context, dbConn *sql.DB, host, domain string, submitter pseudonym.ID) (bool, error) {
+	domainInserted, err := insertHost(ctx, dbConn, host, domain, submitter)
+	if nil != err {
+		if isUniqueViolation(err) {
+			return false, db.ErrDuplicateHost
+		}
+		if IsBusy(err) {
+			return false, db.ErrBusy
+		}
+		return false, fmt.Errorf("db: failed to insert host into database: %v", err)
+	}
+
+	return domainInserted, nil
+}
+
+func insertHost(ctx context.Context, dbConn *sql.DB, host, domain string, submitter pseudonym.ID) (bool, error) {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Unix()
+	createdByID := int64(submitter)
+	res, err := table.Domains.
+		INSERT(table.Domains.AllColumns).
+		MODEL(pgmodel.Domains{Domain: domain, CreatedTs: now, CreatedByID: &createdByID}).
+		ON_CONFLICT(table.Domains.Domain).
+		DO_NOTHING().
+		ExecContext(ctx, tx)
+	if nil != err {
+		return false, err
+	}
+	affectedRows, err := res.RowsAffected()
+	if nil != err {
+		return false, fmt.Errorf("failed to get number of affected rows by domain insert query: %v", err)
+	}
+
+	_, err = table.Hosts.
+		INSERT(table.Hosts.AllColumns).
+		MODEL(pgmodel.Hosts{Host: host, Domain: domain, CreatedTs: now, CreatedByID: &createdByID}).
+		ExecContext(ctx, tx)
+	if nil != err {
+		return false, err
+	}
+
+	return affectedRows == 1, tx.Commit()
+}
+
+func ListHosts(ctx context.Context, dbConn *sql.DB, before time.Time) ([]model.Hosts, error) {
+	var dest []pgmodel.Hosts
+	err := table.Hosts.
+		SELECT(table.Hosts.AllColumns).
+		WHERE(table.Hosts.CreatedTs.LT(postgres.Int64(before.Unix()))).
+		ORDER_BY(table.Hosts.Host.ASC()).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		if IsBusy(err) {
+			return nil, db.ErrBusy
+		}
+		return nil, fmt.Errorf("db: failed to list hosts: %v", err)
+	}
+
+	res := make([]model.Hosts, 0, len(dest))
+	for _, h := range dest {
+		res = append(res, model.Hosts(h))
+	}
+	return res, nil
+}
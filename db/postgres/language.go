@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+func UserLanguage(ctx context.Context, dbConn *sql.DB, userID pseudonym.ID) (string, error) {
+	var dest model.UsersLanguage
+	err := table.UsersLanguage.
+		SELECT(table.UsersLanguage.AllColumns).
+		WHERE(table.UsersLanguage.TheUserID.EQ(postgres.Int64(int64(userID)))).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return "", nil
+		}
+		if IsBusy(err) {
+			return "", db.ErrBusy
+		}
+		return "", fmt.Errorf("db: failed to query user language: %v", err)
+	}
+
+	return dest.Language, nil
+}
+
+func SetUserLanguage(ctx context.Context, dbConn *sql.DB, userID pseudonym.ID, lang string) error {
+	_, err := table.UsersLanguage.
+		INSERT(table.UsersLanguage.AllColumns).
+		MODEL(model.UsersLanguage{TheUserID: int64(userID), Language: lang, UpdatedTs: time.Now().UTC().Unix()}).
+		ON_CONFLICT(table.UsersLanguage.TheUserID).
+		DO_UPDATE(postgres.SET(
+			table.UsersLanguage.Language.SET(table.UsersLanguage.EXCLUDED.Language),
+			table.UsersLanguage.UpdatedTs.SET(table.UsersLanguage.EXCLUDED.UpdatedTs),
+		)).
+		ExecContext(ctx, dbConn)
+	if nil != err {
+		if IsBusy(err) {
+			return db.ErrBusy
+		}
+		return fmt.Errorf("db: failed to update user language: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE domains (
+	domain TEXT NOT NULL PRIMARY KEY,
+	created_ts BIGINT NOT NULL,
+	created_by_id BIGINT
+);
+
+CREATE TABLE users_rate_limit (
+	the_user_id BIGINT NOT NULL,
+	outcome TEXT NOT NULL,
+	window_seconds BIGINT NOT NULL,
+	theoretical_arrival_ms BIGINT NOT NULL,
+	PRIMARY KEY (the_user_id, outcome, window_seconds)
+);
+
+-- +goose Down
+DROP TABLE users_rate_limit;
+DROP TABLE domains;
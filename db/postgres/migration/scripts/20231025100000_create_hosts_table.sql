@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE hosts (
+	host TEXT NOT NULL PRIMARY KEY,
+	domain TEXT NOT NULL REFERENCES domains (domain) ON DELETE CASCADE,
+	created_ts BIGINT NOT NULL
+);
+CREATE INDEX hosts_domain_idx ON hosts (domain);
+
+-- Domains were submitted as apexes until hosts were tracked.
+INSERT INTO hosts (host, domain, created_ts) SELECT domain, domain, created_ts FROM domains;
+
+-- +goose Down
+DROP TABLE hosts;
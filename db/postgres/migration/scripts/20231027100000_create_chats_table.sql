@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE chats (
+	the_chat_id BIGINT NOT NULL PRIMARY KEY,
+	silent BOOLEAN NOT NULL DEFAULT FALSE,
+	admins_only BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_ts BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE chats;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Submissions are listed, and counted, by hosts.created_by_id since 20231030100000, so the index is no longer used.
+DROP INDEX domains_created_by_id_idx;
+
+-- +goose Down
+CREATE INDEX domains_created_by_id_idx ON domains (created_by_id, created_ts);
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/lib/pq"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	pgmodel "github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+)
+
+// IsBusy reports whether the statement failed due to concurrent transactions, and may succeed if retried.
+func IsBusy(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "serialization_failure", "deadlock_detected", "lock_not_available", "too_many_connections":
+			return true
+		}
+	}
+	return false
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation"
+}
+
+// FindDomain returns the row type generated for sqlite, which is identical, so callers don't depend on the dialect.
+func FindDomain(ctx context.Context, dbConn *sql.DB, domain string) (*model.Domains, error) {
+	var dest pgmodel.Domains
+	err := table.Domains.
+		SELECT(table.Domains.AllColumns).
+		WHERE(table.Domains.Domain.EQ(postgres.String(domain))).
+		LIMIT(1).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, db.ErrDomainNotFound
+		}
+		if IsBusy(err) {
+			return nil, db.ErrBusy
+		}
+		return nil, fmt.Errorf("db: failed to query domain from database: %v", err)
+	}
+
+	res := model.Domains(dest)
+	return &res, nil
+}
+
+func ListDomains(ctx context.Context, dbConn *sql.DB, before time.Time) ([]model.Domains, error) {
+	var dest []pgmodel.Domains
+	err := table.Domains.
+		SELECT(table.Domains.AllColumns).
+		WHERE(table.Domains.CreatedTs.LT(postgres.Int64(before.Unix()))).
+		ORDER_BY(table.Domains.Domain.ASC()).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		if IsBusy(err) {
+			return nil, db.ErrBusy
+		}
+		return nil, fmt.Errorf("db: failed to list domains: %v", err)
+	}
+
+	res := make([]model.Domains, 0, len(dest))
+	for _, d := range dest {
+		res = append(res, model.Domains(d))
+	}
+	return res, nil
+}
+
+func Ping(ctx context.Context, dbConn *sql.DB) error {
+	query, args := table.Domains.SELECT(table.Domains.Domain).LIMIT(1).Sql()
+	var domain string
+	if err := dbConn.QueryRowContext(ctx, query, args...).Scan(&domain); nil != err && !errors.Is(err, sql.ErrNoRows) {
+		if IsBusy(err) {
+			return db.ErrBusy
+		}
+		return fmt.Errorf("db: failed to ping database: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+func CountDomains(ctx context.Context, dbConn *sql.DB, day, week time.Time) (db.DomainCounts, error) {
+	query, args := postgres.SELECT(
+		postgres.COUNT(postgres.STAR),
+		postgres.COUNT(postgres.CASE().WHEN(table.Domains.CreatedTs.GT_EQ(postgres.Int64(day.Unix()))).THEN(postgres.Int(1))),
+		postgres.COUNT(postgres.CASE().WHEN(table.Domains.CreatedTs.GT_EQ(postgres.Int64(week.Unix()))).THEN(postgres.Int(1))),
+	).FROM(table.Domains).Sql()
+	var res db.DomainCounts
+	if err := dbConn.QueryRowContext(ctx, query, args...).Scan(&res.Total, &res.Today, &res.ThisWeek); nil != err {
+		if IsBusy(err) {
+			return db.DomainCounts{}, db.ErrBusy
+		}
+		return db.DomainCounts{}, fmt.Errorf("db: failed to count domains: %v", err)
+	}
+
+	return res, nil
+}
+
+func CountSubmissions(ctx context.Context, dbConn *sql.DB, submitter pseudonym.ID) (int, error) {
+	query, args := postgres.SELECT(postgres.COUNT(postgres.STAR)).
+		FROM(table.Hosts).
+		WHERE(table.Hosts.CreatedByID.EQ(postgres.Int64(int64(submitter)))).
+		Sql()
+	var n int
+	if err := dbConn.QueryRowContext(ctx, query, args...).Scan(&n); nil != err {
+		if IsBusy(err) {
+			return 0, db.ErrBusy
+		}
+		return 0, fmt.Errorf("db: failed to count submissions: %v", err)
+	}
+
+	return n, nil
+}
+
+func TopSubmitters(ctx context.Context, dbConn *sql.DB, limit int) ([]db.Submitter, error) {
+	hosts := postgres.COUNT(postgres.STAR)
+	query, args := table.Hosts.
+		LEFT_JOIN(table.UsersPublicName, table.UsersPublicName.TheUserID.EQ(table.Hosts.CreatedByID)).
+		SELECT(table.Hosts.CreatedByID, hosts, table.UsersPublicName.Name).
+		WHERE(table.Hosts.CreatedByID.IS_NOT_NULL()).
+		GROUP_BY(table.Hosts.CreatedByID, table.UsersPublicName.Name).
+		ORDER_BY(hosts.DESC(), table.Hosts.CreatedByID.ASC()).
+		LIMIT(int64(limit)).
+		Sql()
+	rows, err := dbConn.QueryContext(ctx, query, args...)
+	if nil != err {
+		if IsBusy(err) {
+			return nil, db.ErrBusy
+		}
+		return nil, fmt.Errorf("db: failed to query top submitters: %v", err)
+	}
+	defer rows.Close()
+
+	var res []db.Submitter
+	for rows.Next() {
+		var s db.Submitter
+		var name sql.NullString
+		if err := rows.Scan(&s.ID, &s.Hosts, &name); nil != err {
+			return nil, fmt.Errorf("db: failed to scan top submitters: %v", err)
+		}
+		s.Name = name.String
+		res = append(res, s)
+	}
+	if err := rows.Err(); nil != err {
+		return nil, fmt.Errorf("db: failed to query top submitters: %v", err)
+	}
+
+	return res, nil
+}
+
+func PublicName(ctx context.Context, dbConn *sql.DB, userID pseudonym.ID) (string, error) {
+	var dest model.UsersPublicName
+	err := table.UsersPublicName.
+		SELECT(table.UsersPublicName.AllColumns).
+		WHERE(table.UsersPublicName.TheUserID.EQ(postgres.Int64(int64(userID)))).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return "", nil
+		}
+		if IsBusy(err) {
+			return "", db.ErrBusy
+		}
+		return "", fmt.Errorf("db: failed to query user public name: %v", err)
+	}
+
+	return dest.Name, nil
+}
+
+func SetPublicName(ctx context.Context, dbConn *sql.DB, userID pseudonym.ID, name string) error {
+	var err error
+	if name == "" {
+		_, err = table.UsersPublicName.
+			DELETE().
+			WHERE(table.UsersPublicName.TheUserID.EQ(postgres.Int64(int64(userID)))).
+			ExecContext(ctx, dbConn)
+	} else {
+		_, err = table.UsersPublicName.
+			INSERT(table.UsersPublicName.AllColumns).
+			MODEL(model.UsersPublicName{TheUserID: int64(userID), Name: name, UpdatedTs: time.Now().UTC().Unix()}).
+			ON_CONFLICT(table.UsersPublicName.TheUserID).
+			DO_UPDATE(postgres.SET(
+				table.UsersPublicName.Name.SET(table.UsersPublicName.EXCLUDED.Name),
+				table.UsersPublicName.UpdatedTs.SET(table.UsersPublicName.EXCLUDED.UpdatedTs),
+			)).
+			ExecContext(ctx, dbConn)
+	}
+	if nil != err {
+		if IsBusy(err) {
+			return db.ErrBusy
+		}
+		return fmt.Errorf("db: failed to update user public name: %v", err)
+	}
+
+	return nil
+}
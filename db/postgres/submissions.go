@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	pgmodel "github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// ListSubmissions returns up to limit of the hosts linked to the submitter, most recent first, after skipping offset of them,
+// with their tags, and the number of all hosts linked to the submitter. Publishing isn't supported with postgres, so none of them are published.
+func ListSubmissions(ctx context.Context, dbConn *sql.DB, submitter pseudonym.ID, offset, limit int) ([]db.Submission, int, error) {
+	total, err := CountSubmissions(ctx, dbConn, submitter)
+	if nil != err {
+		return nil, 0, err
+	}
+
+	var dest []pgmodel.Hosts
+	err = table.Hosts.
+		SELECT(table.Hosts.AllColumns).
+		WHERE(table.Hosts.CreatedByID.EQ(postgres.Int64(int64(submitter)))).
+		ORDER_BY(table.Hosts.CreatedTs.DESC(), table.Hosts.Host.ASC()).
+		LIMIT(int64(limit)).
+		OFFSET(int64(offset)).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		if IsBusy(err) {
+			return nil, 0, db.ErrBusy
+		}
+		return nil, 0, fmt.Errorf("db: failed to list submissions: %v", err)
+	}
+	if len(dest) == 0 {
+		return nil, total, nil
+	}
+
+	domains := make([]postgres.Expression, 0, len(dest))
+	for _, h := range dest {
+		domains = append(domains, postgres.String(h.Domain))
+	}
+	tags, err := listDomainTags(ctx, dbConn, table.DomainTags.Domain.IN(domains...))
+	if nil != err {
+		return nil, 0, err
+	}
+	res := make([]db.Submission, 0, len(dest))
+	for _, h := range dest {
+		res = append(res, db.Submission{Hosts: model.Hosts(h), Tags: tags[h.Domain]})
+	}
+
+	return res, total, nil
+}
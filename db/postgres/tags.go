@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/postgres"
+	"github.com/go-jet/jet/v2/qrm"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	pgmodel "github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+)
+
+func TagDomain(ctx context.Context, dbConn *sql.DB, domain, tag string, create bool) (bool, error) {
+	return wrapTagDomain(tagDomain(ctx, dbConn, domain, tag, create, false))
+}
+
+// TagUntaggedDomain locks the row of the domain, so that concurrent calls are serialized, and only the first one
+// of them finds the domain without tags.
+func TagUntaggedDomain(ctx context.Context, dbConn *sql.DB, domain, tag string) (bool, error) {
+	return wrapTagDomain(tagDomain(ctx, dbConn, domain, tag, false, true))
+}
+
+func wrapTagDomain(added bool, err error) (bool, error) {
+	if nil != err {
+		if errors.Is(err, db.ErrDomainNotFound) || errors.Is(err, db.ErrTagNotFound) {
+			return false, err
+		}
+		if IsBusy(err) {
+			return false, db.ErrBusy
+		}
+		return false, fmt.Errorf("db: failed to tag domain: %v", err)
+	}
+
+	return added, nil
+}
+
+func tagDomain(ctx context.Context, dbConn *sql.DB, domain, tag string, create, untaggedOnly bool) (bool, error) {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var d pgmodel.Domains
+	lookup := table.Domains.
+		SELECT(table.Domains.Domain).
+		WHERE(table.Domains.Domain.EQ(postgres.String(domain)))
+	if untaggedOnly {
+		lookup = lookup.FOR(postgres.UPDATE())
+	}
+	err = lookup.QueryContext(ctx, tx, &d)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return false, db.ErrDomainNotFound
+		}
+		return false, err
+	}
+
+	now := time.Now().UTC().Unix()
+	if create {
+		_, err = table.Tags.
+			INSERT(table.Tags.Name, table.Tags.CreatedTs).
+			VALUES(tag, now).
+			ON_CONFLICT(table.Tags.Name).
+			DO_NOTHING().
+			ExecContext(ctx, tx)
+		if nil != err {
+			return false, err
+		}
+	}
+
+	var t pgmodel.Tags
+	err = table.Tags.
+		SELECT(table.Tags.AllColumns).
+		WHERE(table.Tags.Name.EQ(postgres.String(tag))).
+		QueryContext(ctx, tx, &t)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return false, db.ErrTagNotFound
+		}
+		return false, err
+	}
+
+	stmt := table.DomainTags.
+		INSERT(table.DomainTags.AllColumns).
+		MODEL(pgmodel.DomainTags{Domain: domain, TagID: t.ID, CreatedTs: now}).
+		ON_CONFLICT(table.DomainTags.Domain, table.DomainTags.TagID).
+		DO_NOTHING()
+	if untaggedOnly {
+		stmt = table.DomainTags.
+			INSERT(table.DomainTags.AllColumns).
+			QUERY(
+				postgres.SELECT(postgres.String(domain), postgres.Int32(t.ID), postgres.Int64(now)).
+					WHERE(postgres.NOT(postgres.EXISTS(
+						table.DomainTags.SELECT(table.DomainTags.Domain).WHERE(table.DomainTags.Domain.EQ(postgres.String(domain))),
+					))),
+			)
+	}
+	res, err := stmt.ExecContext(ctx, tx)
+	if nil != err {
+		return false, err
+	}
+	affectedRows, err := res.RowsAffected()
+	if nil != err {
+		return false, fmt.Errorf("failed to get number of affected rows by domain tag insert query: %v", err)
+	}
+
+	return affectedRows == 1, tx.Commit()
+}
+
+func UntagDomain(ctx context.Context, dbConn *sql.DB, domain, tag string) (bool, error) {
+	res, err := table.DomainTags.
+		DELETE().
+		WHERE(
+			table.DomainTags.Domain.EQ(postgres.String(domain)).
+				AND(table.DomainTags.TagID.IN(
+					table.Tags.SELECT(table.Tags.ID).WHERE(table.Tags.Name.EQ(postgres.String(tag))),
+				)),
+		).
+		ExecContext(ctx, dbConn)
+	if nil != err {
+		if IsBusy(err) {
+			return false, db.ErrBusy
+		}
+		return false, fmt.Errorf("db: failed to untag domain: %v", err)
+	}
+	affectedRows, err := res.RowsAffected()
+	if nil != err {
+		return false, fmt.Errorf("db: failed to get number of affected rows by domain tag delete query: %v", err)
+	}
+
+	return affectedRows > 0, nil
+}
+
+func ListTags(ctx context.Context, dbConn *sql.DB) ([]model.Tags, error) {
+	var dest []pgmodel.Tags
+	err := table.Tags.
+		SELECT(table.Tags.AllColumns).
+		ORDER_BY(table.Tags.Name.ASC()).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		if IsBusy(err) {
+			return nil, db.ErrBusy
+		}
+		return nil, fmt.Errorf("db: failed to list tags: %v", err)
+	}
+
+	res := make([]model.Tags, 0, len(dest))
+	for _, t := range dest {
+		res = append(res, model.Tags(t))
+	}
+	return res, nil
+}
+
+func DomainTags(ctx context.Context, dbConn *sql.DB, domain string) ([]string, error) {
+	tags, err := listDomainTags(ctx, dbConn, table.DomainTags.Domain.EQ(postgres.String(domain)))
+	if nil != err {
+		return nil, err
+	}
+
+	return tags[domain], nil
+}
+
+func ListDomainTags(ctx context.Context, dbConn *sql.DB) (map[string][]string, error) {
+	return listDomainTags(ctx, dbConn, postgres.Bool(true))
+}
+
+func listDomainTags(ctx context.Context, dbConn *sql.DB, condition postgres.BoolExpression) (map[string][]string, error) {
+	var dest []struct {
+		pgmodel.DomainTags
+		pgmodel.Tags
+	}
+	err := table.DomainTags.
+		INNER_JOIN(table.Tags, table.Tags.ID.EQ(table.DomainTags.TagID)).
+		SELECT(table.DomainTags.Domain, table.DomainTags.TagID, table.Tags.ID, table.Tags.Name).
+		WHERE(condition).
+		ORDER_BY(table.DomainTags.Domain.ASC(), table.Tags.Name.ASC()).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		if IsBusy(err) {
+			return nil, db.ErrBusy
+		}
+		return nil, fmt.Errorf("db: failed to list domain tags: %v", err)
+	}
+
+	res := make(map[string][]string)
+	for _, row := range dest {
+		res[row.DomainTags.Domain] = append(res[row.DomainTags.Domain], row.Tags.Name)
+	}
+	return res, nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// DomainCounts are the numbers of all domains, and of the ones created since the start of the day, and of the week.
+type DomainCounts struct {
+	Total    int
+	Today    int
+	ThisWeek int
+}
+
+// Submitter is a submitter of hosts, by the number of hosts linked to them.
+type Submitter struct {
+	ID pseudonym.ID
+	// Name is the public name of the submitter, or empty if they haven't opted in to have one.
+	Name  string
+	Hosts int
+}
+
+// CountDomains returns the number of all domains, and of the ones created since day, and week, in a single query.
+func CountDomains(ctx context.Context, db *sql.DB, day, week time.Time) (DomainCounts, error) {
+	query, args := sqlite.SELECT(
+		sqlite.COUNT(sqlite.STAR),
+		sqlite.COUNT(sqlite.CASE().WHEN(table.Domains.CreatedTs.GT_EQ(sqlite.Int64(day.Unix()))).THEN(sqlite.Int(1))),
+		sqlite.COUNT(sqlite.CASE().WHEN(table.Domains.CreatedTs.GT_EQ(sqlite.Int64(week.Unix()))).THEN(sqlite.Int(1))),
+	).FROM(table.Domains).Sql()
+	var res DomainCounts
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&res.Total, &res.Today, &res.ThisWeek); nil != err {
+		return DomainCounts{}, WrapErr(err, "failed to count domains")
+	}
+
+	return res, nil
+}
+
+// CountSubmissions returns the number of hosts linked to the submitter.
+func CountSubmissions(ctx context.Context, db *sql.DB, submitter pseudonym.ID) (int, error) {
+	query, args := sqlite.SELECT(sqlite.COUNT(sqlite.STAR)).
+		FROM(table.Hosts).
+		WHERE(table.Hosts.CreatedByID.EQ(sqlite.Int64(int64(submitter)))).
+		Sql()
+	var n int
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&n); nil != err {
+		return 0, WrapErr(err, "failed to count submissions")
+	}
+
+	return n, nil
+}
+
+// TopSubmitters returns up to limit of the submitters with the most hosts linked to them, and their public names.
+// Ties are ordered by pseudonym, which is stable, but doesn't favor anyone in particular.
+func TopSubmitters(ctx context.Context, db *sql.DB, limit int) ([]Submitter, error) {
+	hosts := sqlite.COUNT(sqlite.STAR)
+	query, args := table.Hosts.
+		LEFT_JOIN(table.UsersPublicName, table.UsersPublicName.TheUserID.EQ(table.Hosts.CreatedByID)).
+		SELECT(table.Hosts.CreatedByID, hosts, table.UsersPublicName.Name).
+		WHERE(table.Hosts.CreatedByID.IS_NOT_NULL()).
+		GROUP_BY(table.Hosts.CreatedByID, table.UsersPublicName.Name).
+		ORDER_BY(hosts.DESC(), table.Hosts.CreatedByID.ASC()).
+		LIMIT(int64(limit)).
+		Sql()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if nil != err {
+		return nil, WrapErr(err, "failed to query top submitters")
+	}
+	defer rows.Close()
+
+	var res []Submitter
+	for rows.Next() {
+		var s Submitter
+		var name sql.NullString
+		if err := rows.Scan(&s.ID, &s.Hosts, &name); nil != err {
+			return nil, WrapErr(err, "failed to scan top submitters")
+		}
+		s.Name = name.String
+		res = append(res, s)
+	}
+	if err := rows.Err(); nil != err {
+		return nil, WrapErr(err, "failed to query top submitters")
+	}
+
+	return res, nil
+}
+
+// PublicName returns the name the user opted in to be shown by among the top submitters, or an empty string if they didn't.
+func PublicName(ctx context.Context, db *sql.DB, userID pseudonym.ID) (string, error) {
+	var dest model.UsersPublicName
+	err := table.UsersPublicName.
+		SELECT(table.UsersPublicName.AllColumns).
+		WHERE(table.UsersPublicName.TheUserID.EQ(sqlite.Int64(int64(userID)))).
+		QueryContext(ctx, db, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return "", nil
+		}
+		return "", WrapErr(err, "failed to query user public name")
+	}
+
+	return dest.Name, nil
+}
+
+// SetPublicName sets the public name of the user, or deletes it if name is empty.
+func SetPublicName(ctx context.Context, db *sql.DB, userID pseudonym.ID, name string) error {
+	err := Retry(ctx, func() error {
+		if name == "" {
+			_, err := table.UsersPublicName.
+				DELETE().
+				WHERE(table.UsersPublicName.TheUserID.EQ(sqlite.Int64(int64(userID)))).
+				ExecContext(ctx, db)
+			return err
+		}
+		_, err := table.UsersPublicName.
+			INSERT(table.UsersPublicName.AllColumns).
+			MODEL(model.UsersPublicName{TheUserID: int64(userID), Name: name, UpdatedTs: time.Now().UTC().Unix()}).
+			ON_CONFLICT(table.UsersPublicName.TheUserID).
+			DO_UPDATE(sqlite.SET(
+				table.UsersPublicName.Name.SET(table.UsersPublicName.EXCLUDED.Name),
+				table.UsersPublicName.UpdatedTs.SET(table.UsersPublicName.EXCLUDED.UpdatedTs),
+			)).
+			ExecContext(ctx, db)
+		return err
+	})
+	if nil != err {
+		return WrapErr(err, "failed to update user public name")
+	}
+
+	return nil
+}
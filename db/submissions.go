@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// Submission is a host linked to its submitter, and its status.
+// Hosts are listed once they resolve, and stay listed, as nothing re-validates, or removes, them afterwards.
+type Submission struct {
+	model.Hosts
+	// Tags are the names of the tags of the domain of the host, ordered by name.
+	Tags []string
+	// PublishedTs is the time of the first publication of the list which included the host, or nil if none did yet.
+	PublishedTs *int64
+}
+
+// ListSubmissions returns up to limit of the hosts linked to the submitter, most recent first, after skipping offset of them,
+// with their tags and status, and the number of all hosts linked to the submitter.
+func ListSubmissions(ctx context.Context, db *sql.DB, submitter pseudonym.ID, offset, limit int) ([]Submission, int, error) {
+	total, err := CountSubmissions(ctx, db, submitter)
+	if nil != err {
+		return nil, 0, err
+	}
+
+	// Publications list the hosts created before they were published.
+	publishedTs := sqlite.IntExp(
+		sqlite.SELECT(sqlite.MINi(table.Publications.PublishedTs)).
+			FROM(table.Publications).
+			WHERE(table.Publications.PublishedTs.GT(table.Hosts.CreatedTs)),
+	)
+	var dest []struct {
+		model.Hosts
+		PublishedTs *int64 `alias:"published_ts"`
+	}
+	err = table.Hosts.
+		SELECT(table.Hosts.AllColumns, publishedTs.AS("published_ts")).
+		WHERE(table.Hosts.CreatedByID.EQ(sqlite.Int64(int64(submitter)))).
+		ORDER_BY(table.Hosts.CreatedTs.DESC(), table.Hosts.Host.ASC()).
+		LIMIT(int64(limit)).
+		OFFSET(int64(offset)).
+		QueryContext(ctx, db, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, 0, WrapErr(err, "failed to list submissions")
+	}
+	if len(dest) == 0 {
+		return nil, total, nil
+	}
+
+	domains := make([]sqlite.Expression, 0, len(dest))
+	for _, d := range dest {
+		domains = append(domains, sqlite.String(d.Domain))
+	}
+	tags, err := listDomainTags(ctx, db, table.DomainTags.Domain.IN(domains...))
+	if nil != err {
+		return nil, 0, err
+	}
+	res := make([]Submission, 0, len(dest))
+	for _, d := range dest {
+		res = append(res, Submission{Hosts: d.Hosts, Tags: tags[d.Domain], PublishedTs: d.PublishedTs})
+	}
+
+	return res, total, nil
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+)
+
+var ErrTagNotFound = errors.New("tag does not exist")
+
+var tagNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidTagName reports whether name is a lowercase tag name of at most 32 letters, digits, and hyphens,
+// which keeps tags usable in file names, commands, and callback data.
+func ValidTagName(name string) bool {
+	return tagNameRegex.MatchString(name)
+}
+
+// TagDomain links the domain to the tag, and reports whether the domain wasn't tagged with it before.
+// Unknown tags are created if create is set, otherwise ErrTagNotFound is returned.
+func TagDomain(ctx context.Context, dbConn *sql.DB, domain, tag string, create bool) (bool, error) {
+	return retryTagDomain(ctx, dbConn, domain, tag, create, false)
+}
+
+// TagUntaggedDomain links the domain to the tag only if the domain has no tags, and reports whether it did.
+// The check and the insert are a single statement, so only one of concurrent calls tags the domain.
+// Unknown tags return ErrTagNotFound.
+func TagUntaggedDomain(ctx context.Context, dbConn *sql.DB, domain, tag string) (bool, error) {
+	return retryTagDomain(ctx, dbConn, domain, tag, false, true)
+}
+
+func retryTagDomain(ctx context.Context, dbConn *sql.DB, domain, tag string, create, untaggedOnly bool) (bool, error) {
+	var added bool
+	err := Retry(ctx, func() (err error) {
+		added, err = tagDomain(ctx, dbConn, domain, tag, create, untaggedOnly)
+		return err
+	})
+	if nil != err {
+		if errors.Is(err, ErrDomainNotFound) || errors.Is(err, ErrTagNotFound) {
+			return false, err
+		}
+		return false, WrapErr(err, "failed to tag domain")
+	}
+
+	return added, nil
+}
+
+func tagDomain(ctx context.Context, dbConn *sql.DB, domain, tag string, create, untaggedOnly bool) (bool, error) {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if nil != err {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var d model.Domains
+	err = table.Domains.
+		SELECT(table.Domains.Domain).
+		WHERE(table.Domains.Domain.EQ(sqlite.String(domain))).
+		QueryContext(ctx, tx, &d)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return false, ErrDomainNotFound
+		}
+		return false, err
+	}
+
+	now := time.Now().UTC().Unix()
+	if create {
+		_, err = table.Tags.
+			INSERT(table.Tags.Name, table.Tags.CreatedTs).
+			VALUES(tag, now).
+			ON_CONFLICT(table.Tags.Name).
+			DO_NOTHING().
+			ExecContext(ctx, tx)
+		if nil != err {
+			return false, err
+		}
+	}
+
+	var t model.Tags
+	err = table.Tags.
+		SELECT(table.Tags.AllColumns).
+		WHERE(table.Tags.Name.EQ(sqlite.String(tag))).
+		QueryContext(ctx, tx, &t)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return false, ErrTagNotFound
+		}
+		return false, err
+	}
+
+	stmt := table.DomainTags.
+		INSERT(table.DomainTags.AllColumns).
+		MODEL(model.DomainTags{Domain: domain, TagID: t.ID, CreatedTs: now}).
+		ON_CONFLICT(table.DomainTags.Domain, table.DomainTags.TagID).
+		DO_NOTHING()
+	if untaggedOnly {
+		stmt = table.DomainTags.
+			INSERT(table.DomainTags.AllColumns).
+			QUERY(
+				sqlite.SELECT(sqlite.String(domain), sqlite.Int32(t.ID), sqlite.Int64(now)).
+					WHERE(sqlite.NOT(sqlite.EXISTS(
+						table.DomainTags.SELECT(table.DomainTags.Domain).WHERE(table.DomainTags.Domain.EQ(sqlite.String(domain))),
+					))),
+			)
+	}
+	res, err := stmt.ExecContext(ctx, tx)
+	if nil != err {
+		return false, err
+	}
+	affectedRows, err := res.RowsAffected()
+	if nil != err {
+		return false, fmt.Errorf("failed to get number of affected rows by domain tag insert query: %v", err)
+	}
+
+	return affectedRows == 1, tx.Commit()
+}
+
+// UntagDomain unlinks the domain from the tag, and reports whether the domain was tagged with it.
+func UntagDomain(ctx context.Context, dbConn *sql.DB, domain, tag string) (bool, error) {
+	var res sql.Result
+	err := Retry(ctx, func() (err error) {
+		res, err = table.DomainTags.
+			DELETE().
+			WHERE(
+				table.DomainTags.Domain.EQ(sqlite.String(domain)).
+					AND(table.DomainTags.TagID.IN(
+						table.Tags.SELECT(table.Tags.ID).WHERE(table.Tags.Name.EQ(sqlite.String(tag))),
+					)),
+			).
+			ExecContext(ctx, dbConn)
+		return err
+	})
+	if nil != err {
+		return false, WrapErr(err, "failed to untag domain")
+	}
+	affectedRows, err := res.RowsAffected()
+	if nil != err {
+		return false, fmt.Errorf("db: failed to get number of affected rows by domain tag delete query: %v", err)
+	}
+
+	return affectedRows > 0, nil
+}
+
+// ListTags returns all tags, ordered by name.
+func ListTags(ctx context.Context, dbConn *sql.DB) ([]model.Tags, error) {
+	var dest []model.Tags
+	err := table.Tags.
+		SELECT(table.Tags.AllColumns).
+		ORDER_BY(table.Tags.Name.ASC()).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, WrapErr(err, "failed to list tags")
+	}
+
+	return dest, nil
+}
+
+// DomainTags returns the names of the tags of the domain, ordered by name.
+func DomainTags(ctx context.Context, dbConn *sql.DB, domain string) ([]string, error) {
+	tags, err := listDomainTags(ctx, dbConn, table.DomainTags.Domain.EQ(sqlite.String(domain)))
+	if nil != err {
+		return nil, err
+	}
+
+	return tags[domain], nil
+}
+
+// ListDomainTags returns the names of the tags of every tagged domain, ordered by name.
+func ListDomainTags(ctx context.Context, dbConn *sql.DB) (map[string][]string, error) {
+	return listDomainTags(ctx, dbConn, sqlite.Bool(true))
+}
+
+func listDomainTags(ctx context.Context, dbConn *sql.DB, condition sqlite.BoolExpression) (map[string][]string, error) {
+	var dest []struct {
+		model.DomainTags
+		model.Tags
+	}
+	err := table.DomainTags.
+		INNER_JOIN(table.Tags, table.Tags.ID.EQ(table.DomainTags.TagID)).
+		SELECT(table.DomainTags.Domain, table.DomainTags.TagID, table.Tags.ID, table.Tags.Name).
+		WHERE(condition).
+		ORDER_BY(table.DomainTags.Domain.ASC(), table.Tags.Name.ASC()).
+		QueryContext(ctx, dbConn, &dest)
+	if nil != err && !errors.Is(err, qrm.ErrNoRows) {
+		return nil, WrapErr(err, "failed to list domain tags")
+	}
+
+	res := make(map[string][]string)
+	for _, row := range dest {
+		res[row.DomainTags.Domain] = append(res[row.DomainTags.Domain], row.Tags.Name)
+	}
+	return res, nil
+}
@@ -10,8 +10,12 @@ import (
 	"time"
 )
 
+// upstream is the DNS server domains are resolved by.
+const upstream = "8.8.8.8:53"
+
 type ResolveOption struct {
 	retries int
+	observe func(upstream string, took time.Duration)
 }
 
 type ResolveOptionFunc func(*ResolveOption)
@@ -22,22 +26,33 @@ func WithRetries(count int) ResolveOptionFunc {
 	}
 }
 
+// WithObserver calls fn with the upstream server, and the duration, of every lookup, including retries.
+func WithObserver(fn func(upstream string, took time.Duration)) ResolveOptionFunc {
+	return func(opt *ResolveOption) {
+		opt.observe = fn
+	}
+}
+
+// CheckUpstream returns an error if the upstream server doesn't answer, by looking up the name servers of the root zone.
+func CheckUpstream(ctx context.Context) error {
+	if _, err := resolver().LookupNS(ctx, "."); nil != err {
+		return fmt.Errorf("failed to lookup root name servers from %s: %v", upstream, err)
+	}
+	return nil
+}
+
 func IsDomainResolvable(ctx context.Context, domain string, opts ...ResolveOptionFunc) (bool, error) {
 	var option ResolveOption
 	for _, fn := range opts {
 		fn(&option)
 	}
 
-	r := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: time.Millisecond * time.Duration(10000),
-			}
-			return d.DialContext(ctx, network, "8.8.8.8:53")
-		},
-	}
+	r := resolver()
+	startedAt := time.Now()
 	ips, err := r.LookupHost(ctx, domain)
+	if nil != option.observe {
+		option.observe(upstream, time.Since(startedAt))
+	}
 	if nil != err {
 		if errors.Is(err, os.ErrDeadlineExceeded) {
 			if option.retries > 0 {
@@ -59,3 +74,15 @@ func IsDomainResolvable(ctx context.Context, domain string, opts ...ResolveOptio
 	}
 	return true, nil
 }
+
+func resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{
+				Timeout: time.Millisecond * time.Duration(10000),
+			}
+			return d.DialContext(ctx, network, upstream)
+		},
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDuration parses a duration as time.ParseDuration does, and also a whole number of days, e.g., 30d,
+// for settings which are usually days long.
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if nil != err {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/z4x7k/iran-domains-tg-bot/export"
+)
+
+func exportDomains(log zerolog.Logger) func(*cli.Context) error {
+	return func(cliCtx *cli.Context) error {
+		ctx, cancel := signal.NotifyContext(cliCtx.Context, os.Interrupt)
+		defer cancel()
+
+		formats, err := export.ParseFormats(cliCtx.String(CLIExportCommandFormatFlag))
+		if nil != err || len(formats) != 1 {
+			return fmt.Errorf("export: '%s' must be a single format of %s or %s", CLIExportCommandFormatFlag, export.FormatText, export.FormatJSON)
+		}
+		rules, err := export.ParseRules(cliCtx.String(CLIExportCommandRulesFlag))
+		if nil != err {
+			return fmt.Errorf("export: %v", err)
+		}
+		var filter []string
+		if val := cliCtx.String(CLIExportCommandTagFlag); val != "" {
+			if filter, err = export.ParseTags(val); nil != err {
+				return fmt.Errorf("export: invalid '%s': %v", CLIExportCommandTagFlag, err)
+			}
+		}
+
+		if err := loadEnv(log, cliCtx); nil != err {
+			return err
+		}
+		st, _, closeDB, err := openStore(ctx, log, cliCtx, false)
+		if nil != err {
+			return err
+		}
+		defer closeDB()
+
+		now := time.Now().UTC()
+		domains, err := st.ListDomains(ctx, now.Add(time.Second))
+		if nil != err {
+			return err
+		}
+		hosts, err := st.ListHosts(ctx, now.Add(time.Second))
+		if nil != err {
+			return err
+		}
+		tags, err := st.ListDomainTags(ctx)
+		if nil != err {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if name := cliCtx.String(CLIExportCommandOutFlag); name != "" {
+			f, err := os.Create(name)
+			if nil != err {
+				return fmt.Errorf("export: failed to create output file: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := export.Write(w, formats[0], rules, export.NewList(domains, hosts, tags, now), filter); nil != err {
+			return fmt.Errorf("export: failed to write domains: %v", err)
+		}
+
+		return nil
+	}
+}
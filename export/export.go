@@ -0,0 +1,228 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+)
+
+type Format string
+
+const (
+	// FormatText lists one domain, or host, per line, which is directly usable in most proxy and routing rule files.
+	FormatText Format = "txt"
+	FormatJSON Format = "json"
+)
+
+// Rules selects the names the text format lists.
+type Rules string
+
+const (
+	// RulesSuffix lists the apex domains, which rules match along with all of their subdomains.
+	RulesSuffix Rules = "suffix"
+	// RulesExact lists the submitted hosts, which rules match exactly.
+	RulesExact Rules = "exact"
+)
+
+// List is the exported domains, with their hosts and tags.
+type List struct {
+	Domains []model.Domains
+	// Hosts holds the submitted hosts of the domains, keyed by domain.
+	Hosts map[string][]model.Hosts
+	// Tags holds the tag names of the domains, keyed by domain.
+	Tags        map[string][]string
+	GeneratedAt time.Time
+}
+
+// NewList groups the hosts by their domain.
+func NewList(domains []model.Domains, hosts []model.Hosts, tags map[string][]string, generatedAt time.Time) List {
+	byDomain := make(map[string][]model.Hosts)
+	for _, h := range hosts {
+		byDomain[h.Domain] = append(byDomain[h.Domain], h)
+	}
+	return List{Domains: domains, Hosts: byDomain, Tags: tags, GeneratedAt: generatedAt}
+}
+
+// Filter returns the list of the domains tagged with any of the filter tags, or the whole list if the filter is empty.
+func (l List) Filter(filter []string) List {
+	if len(filter) == 0 {
+		return l
+	}
+	res := List{Hosts: make(map[string][]model.Hosts), Tags: l.Tags, GeneratedAt: l.GeneratedAt}
+	for _, d := range l.Domains {
+		if hasAnyTag(l.Tags[d.Domain], filter) {
+			res.Domains = append(res.Domains, d)
+		}
+	}
+	for domain, hosts := range l.Hosts {
+		if hasAnyTag(l.Tags[domain], filter) {
+			res.Hosts[domain] = hosts
+		}
+	}
+	return res
+}
+
+// hostNames returns the names of all hosts of the list, ordered by name.
+func (l List) hostNames() []string {
+	var names []string
+	for _, hosts := range l.Hosts {
+		for _, h := range hosts {
+			names = append(names, h.Host)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type jsonList struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Tags        []string     `json:"tags,omitempty"`
+	Count       int          `json:"count"`
+	Domains     []jsonDomain `json:"domains"`
+}
+
+type jsonDomain struct {
+	Domain    string    `json:"domain"`
+	CreatedAt time.Time `json:"created_at"`
+	Hosts     []string  `json:"hosts"`
+	Tags      []string  `json:"tags"`
+}
+
+// ParseFormats parses a comma separated list of formats, e.g. "txt,json".
+func ParseFormats(s string) ([]Format, error) {
+	var formats []Format
+	seen := map[Format]bool{}
+	for _, part := range strings.Split(s, ",") {
+		f := Format(strings.TrimSpace(part))
+		switch f {
+		case FormatText, FormatJSON:
+		default:
+			return nil, fmt.Errorf("export format must be either %s or %s, got '%s'", FormatText, FormatJSON, f)
+		}
+		if seen[f] {
+			return nil, fmt.Errorf("duplicate export format '%s'", f)
+		}
+		seen[f] = true
+		formats = append(formats, f)
+	}
+
+	return formats, nil
+}
+
+func ParseRules(s string) (Rules, error) {
+	switch r := Rules(s); r {
+	case RulesSuffix, RulesExact:
+		return r, nil
+	default:
+		return "", fmt.Errorf("export rules must be either %s or %s, got '%s'", RulesSuffix, RulesExact, s)
+	}
+}
+
+// ParseTags parses a comma separated list of tag names to filter domains by, e.g. "bank,government".
+func ParseTags(s string) ([]string, error) {
+	var tags []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		tag := strings.ToLower(strings.TrimSpace(part))
+		if !db.ValidTagName(tag) {
+			return nil, fmt.Errorf("invalid tag name '%s'", tag)
+		}
+		if seen[tag] {
+			return nil, fmt.Errorf("duplicate tag '%s'", tag)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+func hasAnyTag(tags []string, filter []string) bool {
+	for _, tag := range tags {
+		for _, f := range filter {
+			if tag == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Write writes the list of domains tagged with any of the filter tags, or all domains if the filter is empty, in the given format.
+// The text format lists either the apex domains, or the hosts, as selected by rules, while the JSON format includes both.
+// Submitters are never included.
+func Write(w io.Writer, f Format, rules Rules, list List, filter []string) error {
+	list = list.Filter(filter)
+	switch f {
+	case FormatText:
+		var names []string
+		switch rules {
+		case RulesSuffix:
+			for _, d := range list.Domains {
+				names = append(names, d.Domain)
+			}
+		case RulesExact:
+			names = list.hostNames()
+		default:
+			return fmt.Errorf("unknown export rules '%s'", rules)
+		}
+		bw := bufio.NewWriter(w)
+		for _, name := range names {
+			if _, err := bw.WriteString(name + "\n"); nil != err {
+				return err
+			}
+		}
+		return bw.Flush()
+	case FormatJSON:
+		res := jsonList{GeneratedAt: list.GeneratedAt.UTC(), Tags: filter, Count: len(list.Domains), Domains: make([]jsonDomain, 0, len(list.Domains))}
+		for _, d := range list.Domains {
+			hosts := make([]string, 0, len(list.Hosts[d.Domain]))
+			for _, h := range list.Hosts[d.Domain] {
+				hosts = append(hosts, h.Host)
+			}
+			tags := list.Tags[d.Domain]
+			if nil == tags {
+				tags = []string{}
+			}
+			res.Domains = append(res.Domains, jsonDomain{Domain: d.Domain, CreatedAt: time.Unix(d.CreatedTs, 0).UTC(), Hosts: hosts, Tags: tags})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	default:
+		return fmt.Errorf("unknown export format '%s'", f)
+	}
+}
+
+// WriteChangelog writes the domains, and then the hosts, added between since and until, one per line with their comma separated tags,
+// each preceded by a header. Like Write, only domains tagged with any of the filter tags, and their hosts, are included if the filter isn't empty.
+func WriteChangelog(w io.Writer, added List, filter []string, since time.Time, until time.Time) error {
+	added = added.Filter(filter)
+	var hosts []model.Hosts
+	for _, domainHosts := range added.Hosts {
+		hosts = append(hosts, domainHosts...)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %d domains added from %s until %s", len(added.Domains), since.UTC().Format(time.RFC3339), until.UTC().Format(time.RFC3339))
+	if len(filter) > 0 {
+		fmt.Fprintf(bw, " tagged %s", strings.Join(filter, ", "))
+	}
+	bw.WriteString("\n")
+	for _, d := range added.Domains {
+		fmt.Fprintf(bw, "%s\t%s\t%s\n", d.Domain, time.Unix(d.CreatedTs, 0).UTC().Format(time.RFC3339), strings.Join(added.Tags[d.Domain], ","))
+	}
+	fmt.Fprintf(bw, "# %d hosts added\n", len(hosts))
+	for _, h := range hosts {
+		fmt.Fprintf(bw, "%s\t%s\t%s\n", h.Host, time.Unix(h.CreatedTs, 0).UTC().Format(time.RFC3339), strings.Join(added.Tags[h.Domain], ","))
+	}
+	return bw.Flush()
+}
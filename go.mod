@@ -6,6 +6,7 @@ require (
 	github.com/go-jet/jet/v2 v2.10.0
 	github.com/go-telegram/bot v0.7.13
 	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/pressly/goose/v3 v3.13.4
 	github.com/rs/zerolog v1.29.1
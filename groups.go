@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+)
+
+func groupsEnabledFromEnv() (bool, error) {
+	val, ok := os.LookupEnv(EnvKeyGroupsEnabled)
+	if !ok || val == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(val)
+	if nil != err {
+		return false, fmt.Errorf("'%s' must be a boolean, e.g. true or false", EnvKeyGroupsEnabled)
+	}
+	return enabled, nil
+}
+
+func isGroupChat(chat models.Chat) bool {
+	return chat.Type == "group" || chat.Type == "supergroup"
+}
+
+// parseGroupCommand returns the name, and arguments, of the command in text, which may be addressed to the bot
+// by its username, e.g. /add@bot git.ir, as group members pick commands of multiple bots from the same menu.
+// It returns false if text isn't a command, or is addressed to another bot.
+func parseGroupCommand(text, botUsername string) (string, string, bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	cmd, args := text[1:], ""
+	if i := strings.IndexFunc(cmd, unicode.IsSpace); i >= 0 {
+		cmd, args = cmd[:i], cmd[i:]
+	}
+	name, username, addressed := strings.Cut(cmd, "@")
+	if addressed && !strings.EqualFold(username, botUsername) {
+		return "", "", false
+	}
+	return strings.ToLower(name), strings.TrimSpace(args), true
+}
+
+// matchGroupCommand matches messages of groups with the command, addressed to the bot or not.
+func matchGroupCommand(name, botUsername string) bot.MatchFunc {
+	return func(update *models.Update) bool {
+		if nil == update.Message || !isGroupChat(update.Message.Chat) {
+			return false
+		}
+		cmd, _, ok := parseGroupCommand(update.Message.Text, botUsername)
+		return ok && cmd == name
+	}
+}
+
+// withoutMention returns the text of msg without the mentions of the bot, and whether it had any. Mentions are found
+// by the entities Telegram marks them with, either @bot, or commands addressed to the bot, e.g. /add@bot, so that
+// they're recognized next to punctuation, e.g. "@bot, git.ir", whose leading, or trailing, separators are removed.
+func withoutMention(msg *models.Message, botUsername string) (string, bool) {
+	// Entity offsets, and lengths, are in UTF-16 code units.
+	text := utf16.Encode([]rune(msg.Text))
+	var (
+		rest      []uint16
+		at        int
+		mentioned bool
+	)
+	for _, e := range msg.Entities {
+		if e.Offset < at || e.Offset+e.Length > len(text) {
+			continue
+		}
+		entity := string(utf16.Decode(text[e.Offset : e.Offset+e.Length]))
+		switch e.Type {
+		case models.MessageEntityTypeMention:
+			if !strings.EqualFold(entity, "@"+botUsername) {
+				continue
+			}
+		case models.MessageEntityTypeBotCommand:
+			if _, username, ok := strings.Cut(entity, "@"); !ok || !strings.EqualFold(username, botUsername) {
+				continue
+			}
+		default:
+			continue
+		}
+		rest = append(rest, text[at:e.Offset]...)
+		at = e.Offset + e.Length
+		mentioned = true
+	}
+	rest = append(rest, text[at:]...)
+	res := strings.Join(strings.Fields(string(utf16.Decode(rest))), " ")
+	return strings.Trim(res, " ,:"), mentioned
+}
+
+// matchGroupMention matches messages of groups, other than commands, which mention the bot.
+func (h *Handler) matchGroupMention(update *models.Update) bool {
+	if nil == update.Message || !isGroupChat(update.Message.Chat) || strings.HasPrefix(update.Message.Text, "/") {
+		return false
+	}
+	_, mentioned := withoutMention(update.Message, h.botUsername)
+	return mentioned
+}
+
+// handleAddCommand submits the domain of /add <domain>, or of the message /add replies to.
+func (h *Handler) handleAddCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscardGroup(update) {
+		return
+	}
+	_, text, _ := parseGroupCommand(update.Message.Text, h.botUsername)
+	h.submitInGroup(ctx, b, update, text)
+}
+
+// handleGroupMention submits the domain of a message mentioning the bot, or of the message it replies to, e.g. "@bot git.ir".
+func (h *Handler) handleGroupMention(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscardGroup(update) {
+		return
+	}
+	text, _ := withoutMention(update.Message, h.botUsername)
+	h.submitInGroup(ctx, b, update, text)
+}
+
+// submitInGroup submits text, or the text of the replied message if it's empty, as permitted by the settings of the group.
+func (h *Handler) submitInGroup(ctx context.Context, b *bot.Bot, update *models.Update, text string) {
+	log := h.loggerFromUpdate(update)
+	msg := update.Message
+	lang := h.userLanguage(ctx, log, msg.From)
+	r := reply{chatID: msg.Chat.ID, messageID: msg.ID}
+
+	settings, err := h.store.ChatSettings(ctx, h.pseudonymizer.ID(msg.Chat.ID))
+	if nil != err {
+		log.Error().Err(err).Msg("failed to query group settings")
+		h.replyInternalError(ctx, b, lang, r)
+		return
+	}
+	r.silent = settings.Silent
+	if settings.AdminsOnly {
+		if !h.checkGroupAdmin(ctx, b, log, lang, r, msg) {
+			return
+		}
+	}
+
+	if text == "" && nil != msg.ReplyToMessage {
+		text = msg.ReplyToMessage.Text
+	}
+	h.submit(ctx, b, log, submission{
+		text:      text,
+		user:      msg.From,
+		messageID: msg.ID,
+		lang:      lang,
+		reply:     r,
+		group:     &settings,
+	})
+}
+
+func (h *Handler) handleSilentCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	h.toggleGroupSetting(ctx, b, update, func(settings *model.Chats) {
+		settings.Silent = !settings.Silent
+	})
+}
+
+func (h *Handler) handleAdminsOnlyCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	h.toggleGroupSetting(ctx, b, update, func(settings *model.Chats) {
+		settings.AdminsOnly = !settings.AdminsOnly
+	})
+}
+
+// toggleGroupSetting changes the settings of the group by toggle, if the user is one of its admins, and replies with the new settings.
+// The reply is sent in silent mode too, as it's asked for.
+func (h *Handler) toggleGroupSetting(ctx context.Context, b *bot.Bot, update *models.Update, toggle func(*model.Chats)) {
+	if shouldDiscardGroup(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	msg := update.Message
+	lang := h.userLanguage(ctx, log, msg.From)
+	r := reply{chatID: msg.Chat.ID, messageID: msg.ID}
+	if !h.checkGroupAdmin(ctx, b, log, lang, r, msg) {
+		return
+	}
+
+	settings, err := h.store.ChatSettings(ctx, h.pseudonymizer.ID(msg.Chat.ID))
+	if nil != err {
+		log.Error().Err(err).Msg("failed to query group settings")
+		h.replyInternalError(ctx, b, lang, r)
+		return
+	}
+	toggle(&settings)
+	if err := h.store.SetChatSettings(ctx, settings); nil != err {
+		log.Error().Err(err).Msg("failed to update group settings")
+		h.replyInternalError(ctx, b, lang, r)
+		return
+	}
+	log.Info().Bool("silent", settings.Silent).Bool("admins_only", settings.AdminsOnly).Msg("updated group settings")
+
+	if _, err := h.sender.SendMessage(ctx, b, &bot.SendMessageParams{
+		ChatID:           r.chatID,
+		ReplyToMessageID: r.messageID,
+		Text:             h.text(lang, i18n.MsgGroupSettings, i18n.Params{"Silent": settings.Silent, "AdminsOnly": settings.AdminsOnly}),
+		ParseMode:        markup.MarkdownV2.ParseMode(),
+	}); nil != err {
+		log.Error().Err(err).Dict("reply_message", h.replyDict(r.chatID)).Msg("failed to send group settings reply message")
+	}
+}
+
+// checkGroupAdmin replies to the user, unless r is silent, and returns false if they aren't an admin of the group of msg.
+func (h *Handler) checkGroupAdmin(ctx context.Context, b *bot.Bot, log zerolog.Logger, lang i18n.Lang, r reply, msg *models.Message) bool {
+	member, err := b.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: msg.Chat.ID, UserID: msg.From.ID})
+	if nil != err {
+		log.Error().Err(err).Msg("failed to get group chat member")
+		h.replyInternalError(ctx, b, lang, r)
+		return false
+	}
+	if member.Type == models.ChatMemberTypeOwner || member.Type == models.ChatMemberTypeAdministrator {
+		return true
+	}
+	if !r.silent {
+		h.replyText(ctx, b, r.chatID, h.text(lang, i18n.MsgGroupAdminsOnly, nil), markup.MarkdownV2.ParseMode())
+	}
+	return false
+}
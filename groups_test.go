@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestParseGroupCommand(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantName string
+		wantArgs string
+		wantOK   bool
+	}{
+		{text: "/add", wantName: "add", wantOK: true},
+		{text: "/add git.ir", wantName: "add", wantArgs: "git.ir", wantOK: true},
+		{text: "/ADD@Bot  git.ir ", wantName: "add", wantArgs: "git.ir", wantOK: true},
+		{text: "/add@bot\ngit.ir", wantName: "add", wantArgs: "git.ir", wantOK: true},
+		{text: "/add@other_bot git.ir", wantOK: false},
+		{text: "git.ir", wantOK: false},
+	}
+	for _, tt := range tests {
+		name, args, ok := parseGroupCommand(tt.text, "bot")
+		if name != tt.wantName || args != tt.wantArgs || ok != tt.wantOK {
+			t.Errorf("parseGroupCommand(%q) = %q, %q, %v, want %q, %q, %v", tt.text, name, args, ok, tt.wantName, tt.wantArgs, tt.wantOK)
+		}
+	}
+}
+
+func TestWithoutMention(t *testing.T) {
+	mention := func(offset, length int) models.MessageEntity {
+		return models.MessageEntity{Type: models.MessageEntityTypeMention, Offset: offset, Length: length}
+	}
+	tests := []struct {
+		name          string
+		text          string
+		entities      []models.MessageEntity
+		wantText      string
+		wantMentioned bool
+	}{
+		{name: "mention", text: "@bot git.ir", entities: []models.MessageEntity{mention(0, 4)}, wantText: "git.ir", wantMentioned: true},
+		{name: "punctuation", text: "@Bot, git.ir", entities: []models.MessageEntity{mention(0, 4)}, wantText: "git.ir", wantMentioned: true},
+		{name: "trailing", text: "git.ir @bot:", entities: []models.MessageEntity{mention(7, 4)}, wantText: "git.ir", wantMentioned: true},
+		// Offsets count UTF-16 code units, of which the emoji takes two.
+		{name: "utf-16 offsets", text: "😀 @bot git.ir", entities: []models.MessageEntity{mention(3, 4)}, wantText: "😀 git.ir", wantMentioned: true},
+		{name: "command", text: "git.ir /add@bot", entities: []models.MessageEntity{{Type: models.MessageEntityTypeBotCommand, Offset: 7, Length: 8}}, wantText: "git.ir", wantMentioned: true},
+		{name: "other bot", text: "@other_bot git.ir /add@other_bot", entities: []models.MessageEntity{mention(0, 10), {Type: models.MessageEntityTypeBotCommand, Offset: 18, Length: 14}}, wantText: "@other_bot git.ir /add@other_bot"},
+		{name: "without entity", text: "@bot git.ir", wantText: "@bot git.ir"},
+		{name: "out of range", text: "@bot", entities: []models.MessageEntity{mention(1, 4)}, wantText: "@bot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, mentioned := withoutMention(&models.Message{Text: tt.text, Entities: tt.entities}, "bot")
+			if text != tt.wantText || mentioned != tt.wantMentioned {
+				t.Errorf("withoutMention(%q) = %q, %v, want %q, %v", tt.text, text, mentioned, tt.wantText, tt.wantMentioned)
+			}
+		})
+	}
+}
@@ -19,3 +19,38 @@ func shouldDiscard(update *models.Update) bool {
 
 	return false
 }
+
+// shouldDiscardGroup is shouldDiscard for messages of groups, which are only handled in group mode.
+func shouldDiscardGroup(update *models.Update) bool {
+	checks := []func() bool{
+		func() bool { return update.Message == nil },
+		func() bool { return update.Message.From == nil },
+		func() bool { return update.Message.From.IsBot },
+		func() bool { return update.Message.Chat.IsForum },
+		func() bool { return !isGroupChat(update.Message.Chat) },
+	}
+	for _, fn := range checks {
+		if fn() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shouldDiscardCallback is shouldDiscard for callback queries of inline keyboards on messages of private chats.
+func shouldDiscardCallback(update *models.Update) bool {
+	checks := []func() bool{
+		func() bool { return update.CallbackQuery == nil },
+		func() bool { return update.CallbackQuery.Sender.IsBot },
+		func() bool { return update.CallbackQuery.Message == nil },
+		func() bool { return update.CallbackQuery.Message.Chat.Type != "private" },
+	}
+	for _, fn := range checks {
+		if fn() {
+			return true
+		}
+	}
+
+	return false
+}
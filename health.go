@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/dns"
+	"github.com/z4x7k/iran-domains-tg-bot/maintenance"
+	"github.com/z4x7k/iran-domains-tg-bot/store"
+	"github.com/z4x7k/iran-domains-tg-bot/systemd"
+)
+
+const (
+	// pollStaleAfter is the age of the last successful getUpdates request after which polling is considered hung,
+	// as long polls return at least every 25 seconds, even if there are no updates.
+	pollStaleAfter = 2 * time.Minute
+	// healthCheckTimeout limits each run of the health checks, so that a locked database fails the check,
+	// rather than waiting for the busy timeout.
+	healthCheckTimeout = 3 * time.Second
+)
+
+// pollTracker records the time of the last successful getUpdates request of the bot.
+type pollTracker struct {
+	lastMs atomic.Int64
+}
+
+// newPollTracker returns a tracker which counts the start of the bot as a successful poll, so that it's alive
+// until the first poll is stale.
+func newPollTracker() *pollTracker {
+	t := &pollTracker{}
+	t.polled()
+	return t
+}
+
+func (t *pollTracker) polled() {
+	t.lastMs.Store(time.Now().UnixMilli())
+}
+
+func (t *pollTracker) check(context.Context) error {
+	if age := time.Since(time.UnixMilli(t.lastMs.Load())); age > pollStaleAfter {
+		return fmt.Errorf("last successful getUpdates request was %s ago", age.Truncate(time.Second))
+	}
+	return nil
+}
+
+type healthCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// maintenanceCheck fails if the last run of any database maintenance task failed, e.g. the integrity check found
+// corruption, except for checkpoints blocked by concurrent queries, which are retried on the next run.
+func maintenanceCheck(s *maintenance.Scheduler) func(context.Context) error {
+	return func(context.Context) error {
+		var failed []string
+		for _, st := range s.Status() {
+			if nil != st.LastErr && !errors.Is(st.LastErr, maintenance.ErrCheckpointBusy) {
+				failed = append(failed, st.LastErr.Error())
+			}
+		}
+		if len(failed) > 0 {
+			return errors.New(strings.Join(failed, "; "))
+		}
+		return nil
+	}
+}
+
+// health holds the checks of liveness, whose failure is only fixed by restarting the bot, and of readiness,
+// which also checks the DNS upstream, as submissions fail without it, and the database maintenance tasks,
+// although restarting doesn't help either.
+type health struct {
+	live  []healthCheck
+	ready []healthCheck
+}
+
+// newHealth returns the checks of the bot. The maintenance scheduler is nil for postgres, which isn't maintained by the bot.
+func newHealth(st store.Store, polls *pollTracker, scheduler *maintenance.Scheduler) *health {
+	db := healthCheck{name: "db", fn: st.Ping}
+	telegram := healthCheck{name: "telegram", fn: polls.check}
+	h := &health{
+		live:  []healthCheck{db, telegram},
+		ready: []healthCheck{db, telegram, {name: "dns", fn: dns.CheckUpstream}},
+	}
+	if nil != scheduler {
+		h.ready = append(h.ready, healthCheck{name: "maintenance", fn: maintenanceCheck(scheduler)})
+	}
+	return h
+}
+
+// runHealthChecks runs the checks concurrently, and returns the errors of the failed ones by their names.
+func runHealthChecks(ctx context.Context, checks []healthCheck) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := make(map[string]error)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c healthCheck) {
+			defer wg.Done()
+			if err := c.fn(ctx); nil != err {
+				mu.Lock()
+				defer mu.Unlock()
+				failed[c.name] = err
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return failed
+}
+
+// healthHandler responds with the result of every check as JSON, e.g. {"status":"fail","checks":{"db":"ok","dns":"..."}},
+// and 503 Service Unavailable if any fails.
+func healthHandler(log zerolog.Logger, checks []healthCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failed := runHealthChecks(r.Context(), checks)
+		res := struct {
+			Status string            `json:"status"`
+			Checks map[string]string `json:"checks"`
+		}{Status: "ok", Checks: make(map[string]string, len(checks))}
+		for _, c := range checks {
+			res.Checks[c.name] = "ok"
+			if err, ok := failed[c.name]; ok {
+				res.Checks[c.name] = err.Error()
+				res.Status = "fail"
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(failed) > 0 {
+			log.Warn().Str("path", r.URL.Path).Interface("checks", res.Checks).Msg("health check failed")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	})
+}
+
+// feedWatchdog notifies the systemd watchdog every half of its interval while the liveness checks pass, so that
+// systemd restarts the bot once it hangs, or the database is locked, for longer than the interval.
+func feedWatchdog(ctx context.Context, log zerolog.Logger, h *health, interval time.Duration) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			failed := runHealthChecks(ctx, h.live)
+			if ctx.Err() != nil {
+				return
+			}
+			if len(failed) > 0 {
+				for name, err := range failed {
+					log.Error().Err(err).Str("check", name).Msg("skipped systemd watchdog notification as liveness check failed")
+				}
+				continue
+			}
+			if _, err := systemd.Notify(systemd.StateWatchdog); nil != err {
+				log.Error().Err(err).Msg("failed to notify systemd watchdog")
+			}
+		}
+	}
+}
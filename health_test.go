@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/dbtest"
+	"github.com/z4x7k/iran-domains-tg-bot/maintenance"
+)
+
+func TestMaintenanceCheck(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	s := maintenance.New(conn, maintenance.DefaultConfig())
+	check := maintenanceCheck(s)
+
+	if err := check(ctx); nil != err {
+		t.Fatalf("check() before any run = %v, want nil", err)
+	}
+	if err := s.RunTask(ctx, maintenance.TaskIntegrityCheck); nil != err {
+		t.Fatalf("RunTask(integrity_check) failed: %v", err)
+	}
+	if err := check(ctx); nil != err {
+		t.Fatalf("check() after a successful run = %v, want nil", err)
+	}
+
+	if err := conn.Close(); nil != err {
+		t.Fatal(err)
+	}
+	if err := s.RunTask(ctx, maintenance.TaskOptimize); nil == err {
+		t.Fatal("RunTask(optimize) succeeded on a closed database")
+	}
+	if err := check(ctx); nil == err {
+		t.Fatal("check() after a failed run = nil, want error")
+	}
+}
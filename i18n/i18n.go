@@ -0,0 +1,430 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+)
+
+//go:embed locales
+var locales embed.FS
+
+// Lang is a lowercase ISO 639 language code, e.g. "en".
+type Lang string
+
+const (
+	English Lang = "en"
+	Persian Lang = "fa"
+	// Fallback is the language of the messages missing in other languages, which must have all messages.
+	Fallback = English
+)
+
+type MessageID string
+
+const (
+	MsgHelp                  MessageID = "help"
+	MsgInfo                  MessageID = "info"
+	MsgDomainAdded           MessageID = "domain_added"
+	MsgCategoryPrompt        MessageID = "category_prompt"
+	MsgDuplicateDomain       MessageID = "duplicate_domain"
+	MsgInternalError         MessageID = "internal_error"
+	MsgRateLimitExceeded     MessageID = "rate_limit_exceeded"
+	MsgThrottled             MessageID = "throttled"
+	MsgInvalidDomain         MessageID = "invalid_domain"
+	MsgAdminOnly             MessageID = "admin_only"
+	MsgCategoryOnlySubmitter MessageID = "category_only_submitter"
+	MsgCategoryAlreadySet    MessageID = "category_already_set"
+	MsgCategoryNotFound      MessageID = "category_not_found"
+	MsgCategorySet           MessageID = "category_set"
+	// MsgCategoryDomainNotFound answers picks from keyboards of domains which are no longer listed.
+	MsgCategoryDomainNotFound MessageID = "category_domain_not_found"
+	MsgLanguagePrompt         MessageID = "language_prompt"
+	MsgLanguageSet            MessageID = "language_set"
+	// MsgLanguageName is the name of the language in itself, e.g. "فارسی".
+	MsgLanguageName MessageID = "language_name"
+	// MsgDescription is shown in the chat with the bot before the user starts it.
+	MsgDescription MessageID = "description"
+	// MsgShortDescription is shown on the profile page of the bot, and along with links to it.
+	MsgShortDescription MessageID = "short_description"
+	// MsgCommand messages describe the commands in the command menu.
+	MsgCommandInfo       MessageID = "command_info"
+	MsgCommandHelp       MessageID = "command_help"
+	MsgCommandLanguage   MessageID = "command_language"
+	MsgCommandTag        MessageID = "command_tag"
+	MsgCommandUntag      MessageID = "command_untag"
+	MsgCommandTags       MessageID = "command_tags"
+	MsgCommandAdd        MessageID = "command_add"
+	MsgCommandSilent     MessageID = "command_silent"
+	MsgCommandAdminsOnly MessageID = "command_admins_only"
+	MsgCommandMine       MessageID = "command_mine"
+	MsgCommandStats      MessageID = "command_stats"
+	MsgCommandPublicName MessageID = "command_public_name"
+	// MsgGroupAdminsOnly is the reply to members of groups, who aren't admins, to admin commands, and submissions in admins only mode.
+	MsgGroupAdminsOnly        MessageID = "group_admins_only"
+	MsgGroupRateLimitExceeded MessageID = "group_rate_limit_exceeded"
+	MsgGroupSettings          MessageID = "group_settings"
+	// MsgSubmissions lists a page of the submissions of the user, and MsgPreviousPage, and MsgNextPage, are the buttons of the adjacent pages.
+	MsgSubmissions   MessageID = "submissions"
+	MsgNoSubmissions MessageID = "no_submissions"
+	MsgPreviousPage  MessageID = "previous_page"
+	MsgNextPage      MessageID = "next_page"
+	// MsgStats messages are the sections of the reply to the stats command, and of the summary posted to the publish chat,
+	// which is MsgStatsSummary followed by MsgStatsDomains, and MsgStatsTop.
+	MsgStatsDomains    MessageID = "stats_domains"
+	MsgStatsTop        MessageID = "stats_top"
+	MsgStatsMine       MessageID = "stats_mine"
+	MsgStatsSummary    MessageID = "stats_summary"
+	MsgPublicNameSet   MessageID = "public_name_set"
+	MsgPublicNameUnset MessageID = "public_name_unset"
+	// MsgTagUsage, MsgInvalidTagName, MsgDomainNotListed, MsgDomainTags, and MsgTagsList, are the replies to the admin
+	// commands managing tags, which are /tag, /untag, and /tags.
+	MsgTagUsage        MessageID = "tag_usage"
+	MsgInvalidTagName  MessageID = "invalid_tag_name"
+	MsgDomainNotListed MessageID = "domain_not_listed"
+	MsgDomainTags      MessageID = "domain_tags"
+	MsgTagsList        MessageID = "tags_list"
+)
+
+// Messages are all message identifiers.
+var Messages = []MessageID{
+	MsgHelp,
+	MsgInfo,
+	MsgDomainAdded,
+	MsgCategoryPrompt,
+	MsgDuplicateDomain,
+	MsgInternalError,
+	MsgRateLimitExceeded,
+	MsgThrottled,
+	MsgInvalidDomain,
+	MsgAdminOnly,
+	MsgCategoryOnlySubmitter,
+	MsgCategoryAlreadySet,
+	MsgCategoryNotFound,
+	MsgCategorySet,
+	MsgCategoryDomainNotFound,
+	MsgLanguagePrompt,
+	MsgLanguageSet,
+	MsgLanguageName,
+	MsgDescription,
+	MsgShortDescription,
+	MsgCommandInfo,
+	MsgCommandHelp,
+	MsgCommandLanguage,
+	MsgCommandTag,
+	MsgCommandUntag,
+	MsgCommandTags,
+	MsgCommandAdd,
+	MsgCommandSilent,
+	MsgCommandAdminsOnly,
+	MsgCommandMine,
+	MsgCommandStats,
+	MsgCommandPublicName,
+	MsgGroupAdminsOnly,
+	MsgGroupRateLimitExceeded,
+	MsgGroupSettings,
+	MsgSubmissions,
+	MsgNoSubmissions,
+	MsgPreviousPage,
+	MsgNextPage,
+	MsgStatsDomains,
+	MsgStatsTop,
+	MsgStatsMine,
+	MsgStatsSummary,
+	MsgPublicNameSet,
+	MsgPublicNameUnset,
+	MsgTagUsage,
+	MsgInvalidTagName,
+	MsgDomainNotListed,
+	MsgDomainTags,
+	MsgTagsList,
+}
+
+// maxLengths are the maximum number of characters of the messages Telegram limits, without their formatting.
+var maxLengths = map[MessageID]int{
+	MsgDescription:       512,
+	MsgShortDescription:  120,
+	MsgCommandInfo:       256,
+	MsgCommandHelp:       256,
+	MsgCommandLanguage:   256,
+	MsgCommandTag:        256,
+	MsgCommandUntag:      256,
+	MsgCommandTags:       256,
+	MsgCommandAdd:        256,
+	MsgCommandSilent:     256,
+	MsgCommandAdminsOnly: 256,
+	MsgCommandMine:       256,
+	MsgCommandStats:      256,
+	MsgCommandPublicName: 256,
+}
+
+// Params are the template parameters of a message, e.g. Since of MsgDuplicateDomain.
+type Params map[string]any
+
+// samples are parameters of the messages that have any, which every template is rendered with when the catalog is loaded.
+var samples = map[MessageID]Params{
+	MsgDomainAdded:            {"Host": "cdn.example.ir"},
+	MsgDuplicateDomain:        {"Since": "2023-10-26"},
+	MsgRateLimitExceeded:      {"RetryAt": "2023-10-26 12:00 UTC"},
+	MsgCategorySet:            {"Tag": "e-commerce"},
+	MsgGroupRateLimitExceeded: {"RetryAt": "2023-10-26 12:00 UTC"},
+	MsgGroupSettings:          {"Silent": true, "AdminsOnly": false},
+	MsgSubmissions: {"From": 11, "To": 12, "Total": 12, "Submissions": []Params{
+		{"Domain": "git.ir", "Since": "2023-10-26", "PublishedAt": "", "Pending": true, "Tags": "e-commerce, news"},
+		{"Domain": "cdn.example.ir", "Since": "2023-10-25", "PublishedAt": "2023-10-26", "Pending": true, "Tags": ""},
+	}},
+	MsgStatsDomains: {"Total": 1200, "Today": 3, "ThisWeek": 40},
+	MsgStatsTop: {"Submitters": []Params{
+		{"Rank": 1, "Name": "Sara_1", "ID": "3f2a1b", "Hosts": 25, "You": false},
+		{"Rank": 2, "Name": "", "ID": "9c0d4e", "Hosts": 12, "You": true},
+	}},
+	MsgStatsMine:       {"Hosts": 12, "Name": "Sara_1"},
+	MsgStatsSummary:    {"At": "2023-10-26 12:00 UTC"},
+	MsgPublicNameSet:   {"Name": "Sara_1"},
+	MsgTagUsage:        {"Command": "untag"},
+	MsgInvalidTagName:  {"Tag": "E_Commerce"},
+	MsgDomainNotListed: {"Domain": "git.ir"},
+	MsgDomainTags:      {"Domain": "git.ir", "Tags": []string{"e-commerce", "news"}},
+	MsgTagsList: {"Total": 2, "Tagged": 3, "Tags": []Params{
+		{"Name": "e-commerce", "Domains": 2},
+		{"Name": "news", "Domains": 1},
+	}},
+}
+
+// funcs are the helpers available in message templates, which escape parameters for MarkdownV2.
+var funcs = template.FuncMap{
+	"escape": markup.MarkdownV2.Escape,
+	"code":   markup.MarkdownV2.Code,
+	"bold":   markup.MarkdownV2.Bold,
+}
+
+var langRegex = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// Catalog holds the message templates of every language.
+type Catalog struct {
+	// dir is the directory of the messages overriding the embedded ones, if not empty.
+	dir string
+
+	mu        sync.RWMutex
+	templates map[Lang]map[MessageID]*template.Template
+	langs     []Lang
+}
+
+// LoadDefault loads the catalog embedded in the binary.
+func LoadDefault() (*Catalog, error) {
+	return LoadDir("")
+}
+
+// LoadDir loads the catalog embedded in the binary, and overrides its messages by the ones in dir, if it's not empty.
+// The directory has the same layout as Load, and may have only some of the messages, or add languages.
+func LoadDir(dir string) (*Catalog, error) {
+	embedded, err := fs.Sub(locales, "locales")
+	if nil != err {
+		return nil, fmt.Errorf("i18n: failed to open embedded locales: %v", err)
+	}
+	fsyss := []fs.FS{embedded}
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if nil != err {
+			return nil, fmt.Errorf("i18n: failed to open templates directory: %v", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("i18n: templates path '%s' is not a directory", dir)
+		}
+		fsyss = append(fsyss, os.DirFS(dir))
+	}
+
+	c, err := Load(fsyss...)
+	if nil != err {
+		return nil, err
+	}
+	c.dir = dir
+	return c, nil
+}
+
+// Load loads a catalog of a directory per language, named by its code, holding a text/template file per message,
+// named by its identifier with a .txt extension, e.g. fa/help.txt. Trailing newlines are trimmed.
+// Messages of later file systems override the ones of earlier file systems.
+//
+// Templates are MarkdownV2, and may use the escape, code, and bold functions for parameters, e.g. {{code .Host}}.
+// Every template is rendered with sample parameters, and an error is returned if any fails, or isn't valid MarkdownV2.
+func Load(fsyss ...fs.FS) (*Catalog, error) {
+	templates := make(map[Lang]map[MessageID]*template.Template)
+	for _, fsys := range fsyss {
+		if err := parse(fsys, templates); nil != err {
+			return nil, err
+		}
+	}
+
+	fallback, ok := templates[Fallback]
+	if !ok {
+		return nil, fmt.Errorf("i18n: fallback language '%s' is missing", Fallback)
+	}
+	var missing []string
+	for _, id := range Messages {
+		if _, ok := fallback[id]; !ok {
+			missing = append(missing, string(id))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("i18n: fallback language '%s' is missing messages: %s", Fallback, strings.Join(missing, ", "))
+	}
+
+	c := &Catalog{templates: templates}
+	for lang, msgs := range templates {
+		if _, ok := msgs[MsgLanguageName]; !ok {
+			return nil, fmt.Errorf("i18n: language '%s' is missing message '%s'", lang, MsgLanguageName)
+		}
+		for id, tmpl := range msgs {
+			text, err := execute(tmpl, samples[id])
+			if nil != err {
+				return nil, fmt.Errorf("i18n: failed to render message '%s' of '%s': %v", id, lang, err)
+			}
+			if err := markup.ValidateMarkdownV2(text); nil != err {
+				return nil, fmt.Errorf("i18n: message '%s' of '%s' is not valid MarkdownV2: %v", id, lang, err)
+			}
+			if limit, ok := maxLengths[id]; ok && utf8.RuneCountInString(markup.StripMarkdownV2(text)) > limit {
+				return nil, fmt.Errorf("i18n: message '%s' of '%s' is longer than %d characters", id, lang, limit)
+			}
+		}
+		c.langs = append(c.langs, lang)
+	}
+	sort.Slice(c.langs, func(i, j int) bool { return c.langs[i] < c.langs[j] })
+
+	return c, nil
+}
+
+// parse adds the templates of fsys to templates, replacing the ones of the same language and message.
+func parse(fsys fs.FS, templates map[Lang]map[MessageID]*template.Template) error {
+	known := make(map[MessageID]bool, len(Messages))
+	for _, id := range Messages {
+		known[id] = true
+	}
+
+	dirs, err := fs.ReadDir(fsys, ".")
+	if nil != err {
+		return fmt.Errorf("i18n: failed to list languages: %v", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		lang := Lang(dir.Name())
+		if !langRegex.MatchString(string(lang)) {
+			return fmt.Errorf("i18n: directory '%s' is not a lowercase ISO 639 language code", lang)
+		}
+		files, err := fs.ReadDir(fsys, dir.Name())
+		if nil != err {
+			return fmt.Errorf("i18n: failed to list messages of '%s': %v", lang, err)
+		}
+		msgs, ok := templates[lang]
+		if !ok {
+			msgs = make(map[MessageID]*template.Template)
+			templates[lang] = msgs
+		}
+		for _, file := range files {
+			if file.IsDir() || path.Ext(file.Name()) != ".txt" {
+				continue
+			}
+			id := MessageID(strings.TrimSuffix(file.Name(), ".txt"))
+			if !known[id] {
+				return fmt.Errorf("i18n: unknown message '%s' in '%s'", id, lang)
+			}
+			content, err := fs.ReadFile(fsys, path.Join(dir.Name(), file.Name()))
+			if nil != err {
+				return fmt.Errorf("i18n: failed to read message '%s' of '%s': %v", id, lang, err)
+			}
+			tmpl, err := template.New(string(id)).Option("missingkey=error").Funcs(funcs).Parse(strings.TrimRight(string(content), "\r\n"))
+			if nil != err {
+				return fmt.Errorf("i18n: invalid message '%s' of '%s': %v", id, lang, err)
+			}
+			msgs[id] = tmpl
+		}
+	}
+
+	return nil
+}
+
+// Reload loads the catalog again from its directory, and keeps the current messages if the directory has invalid ones.
+func (c *Catalog) Reload() error {
+	next, err := LoadDir(c.dir)
+	if nil != err {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.templates = next.templates
+	c.langs = next.langs
+	return nil
+}
+
+// Languages returns the languages of the catalog, ordered by code.
+func (c *Catalog) Languages() []Lang {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.langs
+}
+
+func (c *Catalog) Has(lang Lang) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.templates[lang]
+	return ok
+}
+
+// Match returns the catalog language of an IETF language tag, such as the language code of Telegram users, e.g. "fa-IR".
+func (c *Catalog) Match(tag string) (Lang, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	lang := Lang(base)
+	return lang, c.Has(lang)
+}
+
+// Render executes the template of the message in the language, or the fallback language if the language doesn't have it.
+// The result is MarkdownV2.
+func (c *Catalog) Render(lang Lang, id MessageID, params Params) (string, error) {
+	c.mu.RLock()
+	tmpl, ok := c.templates[lang][id]
+	if !ok {
+		tmpl, ok = c.templates[Fallback][id]
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("i18n: unknown message '%s'", id)
+	}
+	text, err := execute(tmpl, params)
+	if nil != err {
+		return "", fmt.Errorf("i18n: failed to render message '%s' of '%s': %v", id, lang, err)
+	}
+
+	return text, nil
+}
+
+// RenderPlain is like Render, but strips the formatting, e.g. for callback query answers, and keyboard buttons.
+func (c *Catalog) RenderPlain(lang Lang, id MessageID, params Params) (string, error) {
+	text, err := c.Render(lang, id, params)
+	if nil != err {
+		return "", err
+	}
+	return markup.StripMarkdownV2(text), nil
+}
+
+func execute(tmpl *template.Template, params Params) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, params); nil != err {
+		return "", err
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("message is empty")
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,52 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+)
+
+func TestRenderSubmissions(t *testing.T) {
+	c, err := LoadDefault()
+	if nil != err {
+		t.Fatalf("LoadDefault() failed: %v", err)
+	}
+	submission := func(published string, pending bool) Params {
+		return Params{"Domain": "cdn.example.ir", "Since": "2023-10-30", "PublishedAt": published, "Pending": pending, "Tags": "cdn, bank"}
+	}
+	tests := []struct {
+		name       string
+		submission Params
+		// want is the status of the submission in the fallback language, or empty if it's left out.
+		want string
+	}{
+		{name: "published", submission: submission("2023-10-31", true), want: "published on 2023\\-10\\-31"},
+		{name: "awaiting publication", submission: submission("", true), want: "awaiting publication"},
+		{name: "publishing disabled", submission: submission("", false)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, lang := range c.Languages() {
+				text, err := c.Render(lang, MsgSubmissions, Params{"From": 1, "To": 1, "Total": 1, "Submissions": []Params{tt.submission}})
+				if nil != err {
+					t.Fatalf("Render(%s) failed: %v", lang, err)
+				}
+				if err := markup.ValidateMarkdownV2(text); nil != err {
+					t.Errorf("Render(%s) = %q, which isn't valid MarkdownV2: %v", lang, text, err)
+				}
+				if lang != Fallback {
+					continue
+				}
+				for _, status := range []string{"published on", "awaiting publication"} {
+					if got := strings.Contains(text, status); got != (tt.want != "" && strings.HasPrefix(tt.want, status)) {
+						t.Errorf("Render(%s) = %q, want status %q", lang, text, tt.want)
+					}
+				}
+				if tt.want != "" && !strings.Contains(text, tt.want) {
+					t.Errorf("Render(%s) = %q, want status %q", lang, text, tt.want)
+				}
+			}
+		})
+	}
+}
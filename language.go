@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+)
+
+const languageCallbackPrefix = "lang:"
+
+// defaultLanguageFromEnv returns the language of users who have no preference, and whose Telegram language isn't in the catalog.
+func defaultLanguageFromEnv(catalog *i18n.Catalog) (i18n.Lang, error) {
+	lang := i18n.Lang(DefaultLanguage)
+	if val, ok := os.LookupEnv(EnvKeyDefaultLanguage); ok && val != "" {
+		lang = i18n.Lang(val)
+	}
+	if !catalog.Has(lang) {
+		return "", fmt.Errorf("'%s' must be one of the catalog languages, got '%s'", EnvKeyDefaultLanguage, lang)
+	}
+
+	return lang, nil
+}
+
+// userLanguage returns the language the user picked with /language, or their Telegram language,
+// or the default language, whichever is in the catalog first.
+func (h *Handler) userLanguage(ctx context.Context, log zerolog.Logger, user *models.User) i18n.Lang {
+	if nil == user {
+		return h.defaultLang
+	}
+	if lang, err := h.store.UserLanguage(ctx, h.pseudonymizer.ID(user.ID)); nil != err {
+		log.Error().Err(err).Msg("failed to query user language preference")
+	} else if h.catalog.Has(i18n.Lang(lang)) {
+		return i18n.Lang(lang)
+	}
+	if lang, ok := h.catalog.Match(user.LanguageCode); ok {
+		return lang
+	}
+
+	return h.defaultLang
+}
+
+// text renders the message as MarkdownV2, or returns its escaped identifier if the catalog fails to render it, so that replies are still sent.
+func (h *Handler) text(lang i18n.Lang, id i18n.MessageID, params i18n.Params) string {
+	text, err := h.catalog.Render(lang, id, params)
+	if nil != err {
+		h.log.Error().Err(err).Str("lang", string(lang)).Str("message_id", string(id)).Msg("failed to render message")
+		return markup.MarkdownV2.Escape(string(id))
+	}
+	return text
+}
+
+// plainText is like text, but strips the formatting, for callback query answers and keyboard buttons.
+func (h *Handler) plainText(lang i18n.Lang, id i18n.MessageID, params i18n.Params) string {
+	text, err := h.catalog.RenderPlain(lang, id, params)
+	if nil != err {
+		h.log.Error().Err(err).Str("lang", string(lang)).Str("message_id", string(id)).Msg("failed to render message")
+		return string(id)
+	}
+	return text
+}
+
+// reloadCatalogOnHangup reloads the message templates whenever the process receives SIGHUP, until ctx is done,
+// and calls onReload after each successful reload. The current templates are kept if the new ones are invalid.
+func reloadCatalogOnHangup(ctx context.Context, log zerolog.Logger, catalog *i18n.Catalog, onReload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := catalog.Reload(); nil != err {
+				log.Error().Err(err).Msg("failed to reload message templates, and kept the current ones")
+				continue
+			}
+			log.Info().Msg("reloaded message templates")
+			onReload()
+		}
+	}
+}
+
+func (h *Handler) handleLanguageCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscard(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
+
+	var row []models.InlineKeyboardButton
+	for _, l := range h.catalog.Languages() {
+		row = append(row, models.InlineKeyboardButton{Text: h.plainText(l, i18n.MsgLanguageName, nil), CallbackData: languageCallbackPrefix + string(l)})
+	}
+	if _, err := h.sender.SendMessage(ctx, b, &bot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        h.text(lang, i18n.MsgLanguagePrompt, nil),
+		ParseMode:   markup.MarkdownV2.ParseMode(),
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}},
+	}); nil != err {
+		log.Error().Err(err).Msg("failed to send language command reply message")
+	}
+}
+
+// handleLanguageCallback stores the language picked from the keyboard of the language command.
+func (h *Handler) handleLanguageCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscardCallback(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	query := update.CallbackQuery
+
+	lang := i18n.Lang(strings.TrimPrefix(query.Data, languageCallbackPrefix))
+	if !h.catalog.Has(lang) {
+		h.answerCallback(ctx, b, log, query, "")
+		h.removeInlineKeyboard(ctx, b, log, query.Message)
+		return
+	}
+	if err := h.store.SetUserLanguage(ctx, h.pseudonymizer.ID(query.Sender.ID), string(lang)); nil != err {
+		log.Error().Err(err).Str("lang", string(lang)).Msg("failed to store user language preference")
+		h.answerCallback(ctx, b, log, query, h.plainText(h.userLanguage(ctx, log, &query.Sender), i18n.MsgInternalError, nil))
+		return
+	}
+
+	h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgLanguageSet, nil))
+	h.removeInlineKeyboard(ctx, b, log, query.Message)
+}
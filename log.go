@@ -1,20 +1,102 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
 	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog"
 )
 
+type LogPrivacy string
+
+const (
+	// LogPrivacyFull logs raw Telegram identifiers, names, and message texts. Only meant for debugging.
+	LogPrivacyFull LogPrivacy = "full"
+	// LogPrivacyPseudonymous logs users by their pseudonym, as stored in the database, and message texts.
+	LogPrivacyPseudonymous LogPrivacy = "pseudonymous"
+	// LogPrivacyMinimal logs no user information, nor message texts.
+	LogPrivacyMinimal LogPrivacy = "minimal"
+)
+
+func parseLogPrivacy(s string) (LogPrivacy, error) {
+	switch p := LogPrivacy(s); p {
+	case LogPrivacyFull, LogPrivacyPseudonymous, LogPrivacyMinimal:
+		return p, nil
+	default:
+		return "", fmt.Errorf("log privacy must be one of %s, %s, or %s, got '%s'", LogPrivacyFull, LogPrivacyPseudonymous, LogPrivacyMinimal, s)
+	}
+}
+
+func newLogger(format string, level string) (zerolog.Logger, error) {
+	lvl, err := zerolog.ParseLevel(level)
+	if nil != err {
+		return zerolog.Logger{}, fmt.Errorf("invalid log level '%s': %v", level, err)
+	}
+	var out io.Writer
+	switch format {
+	case "console":
+		out = zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) { w.Out = os.Stderr; w.TimeFormat = time.RFC3339 })
+	case "json":
+		out = os.Stderr
+	default:
+		return zerolog.Logger{}, fmt.Errorf("log format must be either console or json, got '%s'", format)
+	}
+
+	return zerolog.New(out).With().Timestamp().Logger().Level(lvl), nil
+}
+
+// updateHook adds the update context to every event, limited to what the privacy policy permits.
+type updateHook struct {
+	h      *Handler
+	update *models.Update
+}
+
+// Run logs callback queries by the message the keyboard is attached to, the user who pressed the button, and its data as the text.
+func (u updateHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	var (
+		msg  *models.Message
+		from *models.User
+		text string
+	)
+	if query := u.update.CallbackQuery; nil != query {
+		msg, from, text = query.Message, &query.Sender, query.Data
+	} else {
+		msg, from, text = u.update.Message, u.update.Message.From, u.update.Message.Text
+	}
+	e.Str("chat_type", msg.Chat.Type).Int("message_date", msg.Date)
+	switch u.h.logPrivacy {
+	case LogPrivacyFull:
+		e.
+			Int64("chat_id", msg.Chat.ID).
+			Str("chat_username", msg.Chat.Username).
+			Str("user_username", from.Username).
+			Str("user_first_name", from.FirstName).
+			Str("user_last_name", from.LastName).
+			Int64("user_id", from.ID).
+			Int64("user_pseudonym", int64(u.h.pseudonymizer.ID(from.ID))).
+			Str("message_text", text)
+	case LogPrivacyPseudonymous:
+		e.
+			Int64("user_pseudonym", int64(u.h.pseudonymizer.ID(from.ID))).
+			Str("message_text", text)
+	}
+}
+
 func (h *Handler) loggerFromUpdate(update *models.Update) zerolog.Logger {
-	return h.log.
-		With().
-		Int64("chat_id", update.Message.Chat.ID).
-		Str("chat_username", update.Message.Chat.Username).
-		Str("user_username", update.Message.From.Username).
-		Str("user_first_name", update.Message.From.FirstName).
-		Str("user_last_name", update.Message.From.LastName).
-		Int64("user_id", update.Message.From.ID).
-		Int("message_date", update.Message.Date).
-		Str("message_text", update.Message.Text).
-		Logger()
+	return h.log.Hook(updateHook{h: h, update: update})
+}
+
+// replyDict identifies the chat of a reply message in logs, as permitted by the privacy policy.
+func (h *Handler) replyDict(chatID int64) *zerolog.Event {
+	switch h.logPrivacy {
+	case LogPrivacyFull:
+		return zerolog.Dict().Int64("chat_id", chatID)
+	case LogPrivacyPseudonymous:
+		return zerolog.Dict().Int64("chat_pseudonym", int64(h.pseudonymizer.ID(chatID)))
+	default:
+		return zerolog.Dict()
+	}
 }
@@ -3,39 +3,116 @@ package main
 import (
 	"context"
 	"database/sql"
-	_ "embed"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
-	"github.com/joho/godotenv"
-	"github.com/mattn/go-sqlite3"
-	"github.com/pressly/goose/v3"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli/v2"
 
+	"github.com/z4x7k/iran-domains-tg-bot/backup"
 	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
 	"github.com/z4x7k/iran-domains-tg-bot/db/migration"
 	"github.com/z4x7k/iran-domains-tg-bot/dns"
+	"github.com/z4x7k/iran-domains-tg-bot/export"
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+	"github.com/z4x7k/iran-domains-tg-bot/maintenance"
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+	"github.com/z4x7k/iran-domains-tg-bot/publish"
 	"github.com/z4x7k/iran-domains-tg-bot/ratelimit"
+	"github.com/z4x7k/iran-domains-tg-bot/retention"
+	"github.com/z4x7k/iran-domains-tg-bot/sender"
+	"github.com/z4x7k/iran-domains-tg-bot/stats"
+	"github.com/z4x7k/iran-domains-tg-bot/store"
+	"github.com/z4x7k/iran-domains-tg-bot/systemd"
 )
 
 const (
-	EnvKeyBotToken               = "BOT_TOKEN"
-	EnvKeyPublishChatID          = "PUBLISH_CHAT_ID"
-	EnvKeyBotHTTPProxyURL        = "BOT_HTTP_PROXY_URL"
-	ParseModeMarkdownV1          = models.ParseMode("Markdown")
-	CLIRunCommandName            = "run"
-	CLIRunCommandDBFileFlag      = "db"
-	CLIRunCommandEnvFileFlag     = "env"
-	RateLimiterMaxAttemptsPerDay = 300
+	EnvKeyBotToken                  = "BOT_TOKEN"
+	EnvKeyPublishChatID             = "PUBLISH_CHAT_ID"
+	EnvKeyBotHTTPProxyURL           = "BOT_HTTP_PROXY_URL"
+	EnvKeyRateLimitSucceeded        = "RATE_LIMIT_SUCCEEDED_POLICY"
+	EnvKeyRateLimitFailed           = "RATE_LIMIT_FAILED_POLICY"
+	EnvKeyRateLimitGroup            = "RATE_LIMIT_GROUP_POLICY"
+	EnvKeyRateLimitPolicyFile       = "RATE_LIMIT_POLICY_FILE"
+	EnvKeyPseudonymizationKey       = "PSEUDONYMIZATION_KEY"
+	EnvKeyRateLimitBackend          = "RATE_LIMIT_BACKEND"
+	EnvKeyRateLimitSnapshot         = "RATE_LIMIT_SNAPSHOT_INTERVAL"
+	EnvKeyRateLimitRedisURL         = "RATE_LIMIT_REDIS_URL"
+	EnvKeyThrottleRate              = "SUBMISSION_THROTTLE_RATE"
+	EnvKeyThrottleBurst             = "SUBMISSION_THROTTLE_BURST"
+	EnvKeyRetentionSubmitterMaxAge  = "RETENTION_SUBMITTER_MAX_AGE"
+	EnvKeyRetentionInterval         = "RETENTION_INTERVAL"
+	EnvKeyBackupDir                 = "BACKUP_DIR"
+	EnvKeyBackupInterval            = "BACKUP_INTERVAL"
+	EnvKeyBackupKeep                = "BACKUP_KEEP"
+	EnvKeyPublishInterval           = "PUBLISH_INTERVAL"
+	EnvKeyPublishFormats            = "PUBLISH_FORMATS"
+	EnvKeyPostgresURL               = "POSTGRES_URL"
+	EnvKeyDBBusyTimeout             = "DB_BUSY_TIMEOUT"
+	EnvKeyAdminUserIDs              = "ADMIN_USER_IDS"
+	EnvKeyPublishTags               = "PUBLISH_TAGS"
+	EnvKeyPublishRules              = "PUBLISH_RULES"
+	EnvKeyDefaultLanguage           = "DEFAULT_LANGUAGE"
+	EnvKeyGroupsEnabled             = "GROUPS_ENABLED"
+	EnvKeyStatsCacheTTL             = "STATS_CACHE_TTL"
+	EnvKeyStatsSummaryInterval      = "STATS_SUMMARY_INTERVAL"
+	EnvKeyMetricsListenAddr         = "METRICS_LISTEN_ADDR"
+	CLIRunCommandName               = "run"
+	CLIRunCommandDBFileFlag         = "db"
+	CLIRunCommandEnvFileFlag        = "env"
+	CLIRunCommandLogLevel           = "log-level"
+	CLIRunCommandLogFormat          = "log-format"
+	CLIRunCommandLogPrivacy         = "log-privacy"
+	CLIRunCommandNoMigrateFlag      = "no-migrate"
+	CLIRunCommandTemplatesDirFlag   = "templates"
+	CLIPurgeCommandName             = "purge"
+	CLIPurgeCommandDryRunFlag       = "dry-run"
+	CLIBackupCommandName            = "backup"
+	CLIBackupCommandDirFlag         = "dir"
+	CLIBackupCommandKeepFlag        = "keep"
+	CLIRestoreCommandName           = "restore"
+	CLIRestoreCommandFromFlag       = "from"
+	CLIPublishCommandName           = "publish"
+	CLIPublishCommandForceFlag      = "force"
+	CLIMigrateCommandName           = "migrate"
+	CLIMigrateStatusCommandName     = "status"
+	CLIMigrateUpCommandName         = "up"
+	CLIMigrateUpToCommandName       = "up-to"
+	CLIMigrateDownCommandName       = "down"
+	CLIMigrateRedoCommandName       = "redo"
+	CLIMigrateVersionCommandName    = "version"
+	CLIExportCommandName            = "export"
+	CLIExportCommandFormatFlag      = "format"
+	CLIExportCommandTagFlag         = "tag"
+	CLIExportCommandOutFlag         = "out"
+	CLIExportCommandRulesFlag       = "rules"
+	DefaultRateLimitSucceeded       = "300/1d"
+	DefaultRateLimitFailed          = "20/1h,100/1d"
+	DefaultRateLimitGroup           = "60/1h,300/1d"
+	DefaultRateLimitSnapshot        = time.Minute
+	DefaultThrottleRate             = 5
+	DefaultThrottleBurst            = 20
+	DefaultRetentionSubmitterMaxAge = 30 * 24 * time.Hour
+	DefaultRetentionInterval        = time.Hour
+	DefaultBackupInterval           = 24 * time.Hour
+	DefaultBackupKeep               = 7
+	DefaultPublishFormats           = "txt,json"
+	DefaultPublishRules             = "suffix"
+	DefaultLanguage                 = "en"
+	DefaultDBBusyTimeout            = 5 * time.Second
+	DefaultStatsCacheTTL            = time.Minute
 )
 
 var (
@@ -62,18 +139,158 @@ func main() {
 			{
 				Name:   CLIRunCommandName,
 				Usage:  "Start the bot server",
-				Action: buildBot(log),
+				Action: buildBot(),
 				Flags: []cli.Flag{
+					envFileFlag(),
+					dbFileFlag(),
 					&cli.StringFlag{
-						Name:     CLIRunCommandEnvFileFlag,
-						Aliases:  []string{"e"},
-						Usage:    "Custom .env file. Defaults to .env in the current working directory",
-						Required: false,
+						Name:  CLIRunCommandLogLevel,
+						Usage: "Minimum log level: trace, debug, info, warn, or error",
+						Value: "info",
 					},
 					&cli.StringFlag{
-						Name:     CLIRunCommandDBFileFlag,
-						Usage:    "Database file name. Defaults to domains.db in the current working directory",
-						Required: false,
+						Name:  CLIRunCommandLogFormat,
+						Usage: "Log output format: console, or json",
+						Value: "console",
+					},
+					&cli.StringFlag{
+						Name:  CLIRunCommandLogPrivacy,
+						Usage: "User information included in logs: full, pseudonymous, or minimal",
+						Value: string(LogPrivacyPseudonymous),
+					},
+					&cli.BoolFlag{
+						Name:  CLIRunCommandNoMigrateFlag,
+						Usage: "Don't apply pending migrations on start, and refuse to start if there are any",
+					},
+					&cli.StringFlag{
+						Name:  CLIRunCommandTemplatesDirFlag,
+						Usage: "Directory of message templates overriding the built-in ones, reloaded on SIGHUP",
+					},
+				},
+			},
+			{
+				Name:   CLIPurgeCommandName,
+				Usage:  "Delete stale rate limit data, and unlink old domains, and hosts, from their submitters",
+				Action: purge(log),
+				Flags: []cli.Flag{
+					envFileFlag(),
+					dbFileFlag(),
+					&cli.BoolFlag{
+						Name:  CLIPurgeCommandDryRunFlag,
+						Usage: "Only report the number of rows that would be purged",
+					},
+				},
+			},
+			{
+				Name:   CLIBackupCommandName,
+				Usage:  "Create a compressed snapshot of the database, which is safe while the bot is running",
+				Action: createBackup(log),
+				Flags: []cli.Flag{
+					envFileFlag(),
+					dbFileFlag(),
+					&cli.StringFlag{
+						Name:  CLIBackupCommandDirFlag,
+						Usage: "Backup directory. Defaults to BACKUP_DIR environment variable",
+					},
+					&cli.IntFlag{
+						Name:  CLIBackupCommandKeepFlag,
+						Usage: "Number of most recent backups to keep, or 0 to keep all. Defaults to BACKUP_KEEP environment variable, or 7",
+					},
+				},
+			},
+			{
+				Name:   CLIRestoreCommandName,
+				Usage:  "Verify a backup, and replace the database with it. The bot must be stopped",
+				Action: restoreBackup(log),
+				Flags: []cli.Flag{
+					dbFileFlag(),
+					&cli.StringFlag{
+						Name:     CLIRestoreCommandFromFlag,
+						Usage:    "Backup file to restore",
+						Required: true,
+					},
+				},
+			},
+			{
+				Name:   CLIPublishCommandName,
+				Usage:  "Post the exported domains list, and the changelog since the last post, to the publish chat",
+				Action: publishNow(log),
+				Flags: []cli.Flag{
+					envFileFlag(),
+					dbFileFlag(),
+					&cli.BoolFlag{
+						Name:  CLIPublishCommandForceFlag,
+						Usage: "Post even if no domains were added since the last post",
+					},
+				},
+			},
+			{
+				Name:   CLIExportCommandName,
+				Usage:  "Write the domains list, optionally filtered by tags, to a file or the standard output",
+				Action: exportDomains(log),
+				Flags: []cli.Flag{
+					envFileFlag(),
+					dbFileFlag(),
+					&cli.StringFlag{
+						Name:  CLIExportCommandFormatFlag,
+						Usage: "Export format: txt, or json",
+						Value: string(export.FormatText),
+					},
+					&cli.StringFlag{
+						Name:  CLIExportCommandRulesFlag,
+						Usage: "Names listed by the txt format: suffix for apex domains, or exact for the submitted hosts",
+						Value: string(export.RulesSuffix),
+					},
+					&cli.StringFlag{
+						Name:  CLIExportCommandTagFlag,
+						Usage: "Comma separated tags. Only domains with any of them are exported",
+					},
+					&cli.StringFlag{
+						Name:  CLIExportCommandOutFlag,
+						Usage: "Output file. Defaults to the standard output",
+					},
+				},
+			},
+			{
+				Name:  CLIMigrateCommandName,
+				Usage: "Inspect, apply, or roll back database migrations",
+				Subcommands: []*cli.Command{
+					{
+						Name:   CLIMigrateStatusCommandName,
+						Usage:  "List applied, and pending, migrations",
+						Action: migrateAction(log, migrateStatus),
+						Flags:  migrateFlags(),
+					},
+					{
+						Name:   CLIMigrateUpCommandName,
+						Usage:  "Apply all pending migrations",
+						Action: migrateAction(log, migrateUp),
+						Flags:  migrateFlags(),
+					},
+					{
+						Name:      CLIMigrateUpToCommandName,
+						Usage:     "Apply pending migrations up to, and including, the given version",
+						ArgsUsage: "VERSION",
+						Action:    migrateAction(log, migrateUpTo),
+						Flags:     migrateFlags(),
+					},
+					{
+						Name:   CLIMigrateDownCommandName,
+						Usage:  "Roll back the latest applied migration",
+						Action: migrateAction(log, migrateDown),
+						Flags:  migrateFlags(),
+					},
+					{
+						Name:   CLIMigrateRedoCommandName,
+						Usage:  "Roll back, and apply again, the latest applied migration",
+						Action: migrateAction(log, migrateRedo),
+						Flags:  migrateFlags(),
+					},
+					{
+						Name:   CLIMigrateVersionCommandName,
+						Usage:  "Print the current database schema version",
+						Action: migrateAction(log, migrateVersion),
+						Flags:  migrateFlags(),
 					},
 				},
 			},
@@ -85,125 +302,468 @@ func main() {
 	}
 }
 
-func buildBot(log zerolog.Logger) func(*cli.Context) error {
+func envFileFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     CLIRunCommandEnvFileFlag,
+		Aliases:  []string{"e"},
+		Usage:    "Custom .env file. Defaults to .env in the current working directory",
+		Required: false,
+	}
+}
+
+func dbFileFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     CLIRunCommandDBFileFlag,
+		Usage:    "Database file name. Defaults to domains.db in the current working directory",
+		Required: false,
+	}
+}
+
+func buildBot() func(*cli.Context) error {
 	return func(cliCtx *cli.Context) error {
 		ctx, cancel := signal.NotifyContext(cliCtx.Context, os.Interrupt)
 		defer cancel()
 
-		envFilename := cliCtx.String(CLIRunCommandEnvFileFlag)
-		if envFilename == "" {
-			envFilename = ".env"
+		log, err := newLogger(cliCtx.String(CLIRunCommandLogFormat), cliCtx.String(CLIRunCommandLogLevel))
+		if nil != err {
+			return fmt.Errorf("log: %v", err)
 		}
-
-		if err := godotenv.Load(envFilename); nil != err {
-			if !errors.Is(err, os.ErrNotExist) {
-				return fmt.Errorf("env: unexpected error while loading environment variables from .env file")
-			}
-			log.Warn().Msg(".env file not found")
+		logPrivacy, err := parseLogPrivacy(cliCtx.String(CLIRunCommandLogPrivacy))
+		if nil != err {
+			return fmt.Errorf("log: %v", err)
 		}
 
-		tz, ok := os.LookupEnv("TZ")
-		if !ok || tz != "UTC" {
-			return errors.New("env: TZ environment variable must be set to UTC")
+		if err := loadEnv(log, cliCtx); nil != err {
+			return err
 		}
 
-		dbFilename := cliCtx.String(CLIRunCommandDBFileFlag)
-		if dbFilename == "" {
-			dbFilename = "domains.db"
+		pseudonymizationKey, ok := os.LookupEnv(EnvKeyPseudonymizationKey)
+		if !ok {
+			return fmt.Errorf("env: required environment variable '%s' is not set", EnvKeyPseudonymizationKey)
+		}
+		pseudonymizer, err := pseudonym.NewFromHex(pseudonymizationKey)
+		if nil != err {
+			return fmt.Errorf("env: invalid '%s': %v", EnvKeyPseudonymizationKey, err)
 		}
+		migration.SetPseudonymizer(pseudonymizer)
 
-		dbConn, err := sql.Open("sqlite3", dbFilename)
+		st, dbConn, closeDB, err := openStore(ctx, log, cliCtx, !cliCtx.Bool(CLIRunCommandNoMigrateFlag))
 		if nil != err {
-			return fmt.Errorf("db: failed to open database: %v", err)
+			return err
 		}
+		defer closeDB()
+		// background holds the periodic tasks, which use the database, so they're stopped, and awaited, before it's closed.
+		var background sync.WaitGroup
 		defer func() {
-			log.Info().Msg("closing database connection")
-			if err := dbConn.Close(); nil != err {
-				log.Error().Err(err).Msg("failed to close database connection")
-			}
+			cancel()
+			background.Wait()
 		}()
-		if err := dbConn.PingContext(ctx); nil != err {
-			return fmt.Errorf("db: failed to ping database connection: %v", err)
-		}
-		sqliteLibVersion, sqliteLibVersionNumber, _ := sqlite3.Version()
-		log.Info().Str("lib_version", sqliteLibVersion).Int("lib_version_number", sqliteLibVersionNumber).Msg("successfully connected to sqlite database")
-		if err := db.ExecPragmas(ctx, dbConn); nil != err {
-			return fmt.Errorf("db: unable to execute database pragmas: %v", err)
+		botMetrics := newBotMetrics()
+		st = store.NewInstrumented(st, botMetrics.observeDBQuery)
+
+		publishChatID, ok := os.LookupEnv(EnvKeyPublishChatID)
+		if !ok {
+			return fmt.Errorf("env: required environment variable '%s' is not set", EnvKeyPublishChatID)
 		}
-		log.Info().Msg("successfully executed database pragmas")
 
-		goose.SetLogger(goose.NopLogger())
-		goose.SetTableName("migrations")
-		goose.SetBaseFS(migration.FS)
-		if err := goose.SetDialect("sqlite3"); nil != err {
-			return fmt.Errorf("db: failed to set goose dialect to sqlite: %v", err)
+		rateLimitPolicy, err := rateLimitPolicyFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
 		}
-		if err := goose.Up(dbConn, "scripts"); nil != err {
-			return fmt.Errorf("db: failed to execute goose migrations: %v", err)
+		rl, stopRateLimiter, err := rateLimiterFromEnv(ctx, log, st, dbConn, rateLimitPolicy)
+		if nil != err {
+			return fmt.Errorf("ratelimit: %v", err)
+		}
+		defer stopRateLimiter()
+		throttle, err := submissionThrottleFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
 		}
-		log.Info().Msg("executed database migrations")
 
-		publishChatID, ok := os.LookupEnv(EnvKeyPublishChatID)
-		if !ok {
-			return fmt.Errorf("env: required environment variable '%s' is not set", EnvKeyPublishChatID)
+		retentionPolicy, err := retentionPolicyFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		retentionInterval, err := retentionIntervalFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		backupConfig, backupEnabled, err := backupConfigFromEnv(cliCtx)
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		backupInterval, err := backupIntervalFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		publishConfig, err := publishConfigFromEnv(cliCtx)
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		publishInterval, publishEnabled, err := publishIntervalFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		admins, err := adminIDsFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		catalog, err := i18n.LoadDir(cliCtx.String(CLIRunCommandTemplatesDirFlag))
+		if nil != err {
+			return err
+		}
+		defaultLang, err := defaultLanguageFromEnv(catalog)
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		groupsEnabled, err := groupsEnabledFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		statsCacheTTL, err := statsCacheTTLFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		statsSummaryInterval, statsSummaryEnabled, err := statsSummaryIntervalFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		metricsListenAddr, metricsEnabled, err := metricsListenAddrFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+		if nil == dbConn {
+			if backupEnabled {
+				return fmt.Errorf("env: '%s' is only supported with sqlite, use pg_dump for postgres", EnvKeyBackupDir)
+			}
+			if publishEnabled {
+				return fmt.Errorf("env: '%s' is only supported with sqlite", EnvKeyPublishInterval)
+			}
 		}
 
-		rl := ratelimit.New(dbConn, RateLimiterMaxAttemptsPerDay, time.Hour*24)
+		if nil != dbConn {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				retention.Run(ctx, dbConn, retentionPolicy, retentionInterval, func(report retention.Report) {
+					logPurgeReport(log, report)
+				}, func(err error) {
+					log.Error().Err(err).Msg("failed to purge stale personal data")
+				})
+			}()
+		} else {
+			log.Warn().Msg("retention purge is only supported with sqlite, and is disabled")
+		}
 
 		handler := Handler{
-			log:           log,
-			publishChatID: publishChatID,
-			db:            dbConn,
-			rateLimiter:   &rl,
+			log:                log,
+			publishChatID:      publishChatID,
+			publishEnabled:     publishEnabled,
+			store:              st,
+			logPrivacy:         logPrivacy,
+			pseudonymizer:      pseudonymizer,
+			rateLimiter:        rl,
+			submissionThrottle: throttle,
+			sender:             sender.New(),
+			admins:             admins,
+			catalog:            catalog,
+			defaultLang:        defaultLang,
+			groupsEnabled:      groupsEnabled,
+			stats:              stats.New(st, statsCacheTTL, statsTopSubmitters),
+			metrics:            botMetrics,
+		}
+		if nil != dbConn {
+			// Switching the auto vacuum mode rebuilds the whole database once, so only run, and migrate, do it.
+			if err := maintenance.EnableIncrementalVacuum(ctx, dbConn); nil != err {
+				return fmt.Errorf("db: %v", err)
+			}
+			handler.maintenance = maintenance.New(dbConn, maintenance.DefaultConfig())
+		}
+
+		polls := newPollTracker()
+		b, err := newBot(func(client bot.HttpClient) bot.HttpClient {
+			return apiClient{client: client, errors: botMetrics.apiErrors, polls: polls}
+		}, bot.WithDefaultHandler(handler.handleMessage), bot.WithMiddlewares(botMetrics.middleware))
+		if nil != err {
+			return err
 		}
+		health := newHealth(st, polls, handler.maintenance)
 
-		httpTransport := http.Transport{IdleConnTimeout: 10 * time.Second, ResponseHeaderTimeout: 30 * time.Second}
-		httpClient := http.Client{Timeout: time.Second * 35, Transport: &httpTransport}
-		proxyURL, ok := os.LookupEnv(EnvKeyBotHTTPProxyURL)
-		if ok && proxyURL != "" {
-			httpProxyURL, err := url.Parse(proxyURL)
+		if metricsEnabled {
+			botMetrics.registry.GaugeFunc("iran_domains_bot_domains", "Number of listed domains, as of the statistics cache.", func() (float64, error) {
+				ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				defer cancel()
+				summary, err := handler.stats.Summary(ctx)
+				return float64(summary.Domains.Total), err
+			})
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", botMetrics.registry.Handler())
+			mux.Handle("/healthz", healthHandler(log, health.live))
+			mux.Handle("/readyz", healthHandler(log, health.ready))
+			if err := serveMetrics(ctx, log, metricsListenAddr, mux); nil != err {
+				return err
+			}
+		}
+
+		if groupsEnabled {
+			me, err := b.GetMe(ctx)
 			if nil != err {
-				return fmt.Errorf("proxy: failed to parse bot http proxy url: %v", err)
+				return fmt.Errorf("bot: failed to get bot username for group mode: %v", err)
 			}
-			httpTransport.Proxy = http.ProxyURL(httpProxyURL)
+			handler.botUsername = me.Username
+			b.RegisterHandlerMatchFunc(handler.matchGroupMention, handler.handleGroupMention)
 		}
+		commands := handler.commands()
+		registerCommands(b, commands, handler.botUsername)
+		b.RegisterHandler(bot.HandlerTypeCallbackQueryData, categoryCallbackPrefix, bot.MatchTypePrefix, handler.handleCategoryCallback)
+		b.RegisterHandler(bot.HandlerTypeCallbackQueryData, languageCallbackPrefix, bot.MatchTypePrefix, handler.handleLanguageCallback)
+		b.RegisterHandler(bot.HandlerTypeCallbackQueryData, mineCallbackPrefix, bot.MatchTypePrefix, handler.handleMineCallback)
+		go handler.publishCommands(ctx, b, commands)
+		go reloadCatalogOnHangup(ctx, log, catalog, func() {
+			handler.publishCommands(ctx, b, commands)
+		})
 
-		opts := []bot.Option{
-			bot.WithCheckInitTimeout(5 * time.Second),
-			bot.WithHTTPClient(25*time.Second, &httpClient),
-			bot.WithDefaultHandler(handler.handleMessage),
+		if nil != handler.maintenance {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				handler.maintenance.Run(ctx, func(st maintenance.Status) {
+					if nil != st.LastErr {
+						log.Error().Err(st.LastErr).Str("task", string(st.Task)).Msg("database maintenance task failed")
+						handler.informSupport(ctx, b, st.LastErr)
+						return
+					}
+					log.Debug().Str("task", string(st.Task)).Dur("took", st.LastDuration).Msg("database maintenance task succeeded")
+				})
+			}()
 		}
 
-		token, ok := os.LookupEnv(EnvKeyBotToken)
-		if !ok {
-			return fmt.Errorf("env: required environment variable '%s' is not set", EnvKeyBotToken)
+		if backupEnabled {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				backup.Run(ctx, dbConn, backupConfig, backupInterval, func(res backup.Result, err error) {
+					if nil != err {
+						log.Error().Err(err).Msg("failed to create scheduled database backup")
+						handler.informSupport(ctx, b, err)
+						return
+					}
+					logBackupResult(log, res)
+				})
+			}()
 		}
 
-		b, err := bot.New(token, opts...)
+		if publishEnabled {
+			publisher := publish.New(dbConn, handler.sender, publishConfig)
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				publisher.Run(ctx, b, publishInterval, func(report publish.Report) {
+					logPublishReport(log, report)
+				}, func(err error) {
+					log.Error().Err(err).Msg("failed to publish domains list")
+					handler.informSupport(ctx, b, err)
+				})
+			}()
+		}
+
+		if statsSummaryEnabled {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				handler.stats.Run(ctx, statsSummaryInterval, func(summary stats.Summary) {
+					handler.postStatsSummary(ctx, b, log, summary)
+				}, func(err error) {
+					log.Error().Err(err).Msg("failed to compute statistics summary")
+					handler.informSupport(ctx, b, err)
+				})
+			}()
+		}
+
+		watchdogInterval, watchdogEnabled, err := systemd.WatchdogInterval()
 		if nil != err {
-			return fmt.Errorf("bot: failed to initialize bot instance: %v", err)
+			log.Error().Err(err).Msg("systemd watchdog is disabled")
+		} else if watchdogEnabled {
+			go feedWatchdog(ctx, log, health, watchdogInterval)
+			log.Info().Dur("interval", watchdogInterval).Msg("feeding systemd watchdog")
+		}
+		if notified, err := systemd.Notify(systemd.StateReady); nil != err {
+			log.Error().Err(err).Msg("failed to notify systemd of readiness")
+		} else if notified {
+			log.Debug().Msg("notified systemd of readiness")
 		}
 
-		b.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypeExact, handler.handleStartCommand)
-		b.RegisterHandler(bot.HandlerTypeMessageText, "/info", bot.MatchTypeExact, handler.handleInfoCommand)
-		b.RegisterHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypeExact, handler.handleHelpCommand)
 		b.Start(ctx)
 
+		if _, err := systemd.Notify(systemd.StateStopping); nil != err {
+			log.Error().Err(err).Msg("failed to notify systemd of stopping")
+		}
+
 		return nil
 	}
 }
 
+// rateLimitPolicyFromEnv returns the default policy, overridden by the policy file, if any, whose outcomes are in turn
+// overridden by their environment variables.
+func rateLimitPolicyFromEnv() (ratelimit.Policy, error) {
+	filePolicy := ratelimit.Policy{}
+	if filename, ok := os.LookupEnv(EnvKeyRateLimitPolicyFile); ok && filename != "" {
+		f, err := os.Open(filename)
+		if nil != err {
+			return nil, fmt.Errorf("failed to open rate limit policy file in '%s': %v", EnvKeyRateLimitPolicyFile, err)
+		}
+		defer f.Close()
+		if filePolicy, err = ratelimit.ParsePolicy(f); nil != err {
+			return nil, fmt.Errorf("invalid rate limit policy file '%s': %v", filename, err)
+		}
+	}
+
+	policy := ratelimit.Policy{}
+	for outcome, v := range map[ratelimit.Outcome][]string{
+		ratelimit.OutcomeSucceeded: {EnvKeyRateLimitSucceeded, DefaultRateLimitSucceeded},
+		ratelimit.OutcomeFailed:    {EnvKeyRateLimitFailed, DefaultRateLimitFailed},
+		ratelimit.OutcomeGroup:     {EnvKeyRateLimitGroup, DefaultRateLimitGroup},
+	} {
+		envKey, rulesStr := v[0], v[1]
+		val, ok := os.LookupEnv(envKey)
+		if !ok || val == "" {
+			if rules, ok := filePolicy[outcome]; ok {
+				policy[outcome] = rules
+				continue
+			}
+		} else {
+			rulesStr = val
+		}
+		rules, err := ratelimit.ParseRules(rulesStr)
+		if nil != err {
+			return nil, fmt.Errorf("invalid rate limit policy in '%s': %v", envKey, err)
+		}
+		policy[outcome] = rules
+	}
+
+	return policy, nil
+}
+
+// rateLimiterFromEnv builds the rate limiter backend selected by the environment.
+// The database backend keeps the state in the store. The sqlite backend name is kept for compatibility.
+// dbConn is the sqlite connection, which is nil for postgres.
+// The returned function stops its background work, and must be called before the database connection is closed.
+func rateLimiterFromEnv(ctx context.Context, log zerolog.Logger, st store.Store, dbConn *sql.DB, policy ratelimit.Policy) (ratelimit.Limiter, func(), error) {
+	backend, _ := os.LookupEnv(EnvKeyRateLimitBackend)
+	switch backend {
+	case "", "database":
+		return st.RateLimiter(policy), func() {}, nil
+	case "sqlite":
+		if nil == dbConn {
+			return nil, nil, fmt.Errorf("sqlite backend in '%s' requires the sqlite database", EnvKeyRateLimitBackend)
+		}
+		return st.RateLimiter(policy), func() {}, nil
+	case "memory":
+		if nil == dbConn {
+			return nil, nil, fmt.Errorf("memory backend in '%s' snapshots into the sqlite database, and is not supported with postgres", EnvKeyRateLimitBackend)
+		}
+		interval := DefaultRateLimitSnapshot
+		if val, ok := os.LookupEnv(EnvKeyRateLimitSnapshot); ok && val != "" {
+			parsed, err := time.ParseDuration(val)
+			if nil != err || parsed <= 0 {
+				return nil, nil, fmt.Errorf("'%s' must be a positive duration", EnvKeyRateLimitSnapshot)
+			}
+			interval = parsed
+		}
+		mem := ratelimit.NewMemory(dbConn, policy)
+		if err := mem.Restore(ctx); nil != err {
+			return nil, nil, fmt.Errorf("failed to restore in-memory rate limiter snapshot: %v", err)
+		}
+		runCtx, cancelRun := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			mem.Run(runCtx, interval, func(err error) {
+				log.Error().Err(err).Msg("failed to snapshot in-memory rate limiter")
+			})
+		}()
+		return mem, func() { cancelRun(); <-done }, nil
+	case "redis":
+		redisURL, ok := os.LookupEnv(EnvKeyRateLimitRedisURL)
+		if !ok || redisURL == "" {
+			return nil, nil, fmt.Errorf("'%s' must be set for redis backend", EnvKeyRateLimitRedisURL)
+		}
+		r, err := ratelimit.NewRedis(redisURL, policy)
+		if nil != err {
+			return nil, nil, err
+		}
+		if err := r.Ping(ctx); nil != err {
+			return nil, nil, err
+		}
+		return r, func() {
+			if err := r.Close(); nil != err {
+				log.Error().Err(err).Msg("failed to close redis rate limiter connection")
+			}
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported backend '%s' in '%s'", backend, EnvKeyRateLimitBackend)
+	}
+}
+
+// submissionThrottleFromEnv builds the global token bucket shared by all users,
+// which protects the DNS upstream from floods of submissions from many accounts.
+func submissionThrottleFromEnv() (*ratelimit.TokenBucket, error) {
+	rate := float64(DefaultThrottleRate)
+	if val, ok := os.LookupEnv(EnvKeyThrottleRate); ok && val != "" {
+		parsed, err := strconv.ParseFloat(val, 64)
+		if nil != err || parsed <= 0 {
+			return nil, fmt.Errorf("'%s' must be a positive number of submissions per second", EnvKeyThrottleRate)
+		}
+		rate = parsed
+	}
+	burst := DefaultThrottleBurst
+	if val, ok := os.LookupEnv(EnvKeyThrottleBurst); ok && val != "" {
+		parsed, err := strconv.Atoi(val)
+		if nil != err || parsed < 1 {
+			return nil, fmt.Errorf("'%s' must be a positive integer", EnvKeyThrottleBurst)
+		}
+		burst = parsed
+	}
+
+	return ratelimit.NewTokenBucket(rate, burst), nil
+}
+
 type Handler struct {
 	log           zerolog.Logger
 	publishChatID string
-	db            *sql.DB
-	rateLimiter   *ratelimit.RateLimiter
+	// publishEnabled is whether the domains list is published periodically.
+	publishEnabled     bool
+	store              store.Store
+	logPrivacy         LogPrivacy
+	pseudonymizer      *pseudonym.Pseudonymizer
+	rateLimiter        ratelimit.Limiter
+	submissionThrottle *ratelimit.TokenBucket
+	sender             *sender.Scheduler
+	maintenance        *maintenance.Scheduler
+	// admins are the Telegram user identifiers permitted to tag domains.
+	admins      map[int64]bool
+	catalog     *i18n.Catalog
+	defaultLang i18n.Lang
+	// groupsEnabled is whether submissions are accepted in groups.
+	groupsEnabled bool
+	// botUsername is the username of the bot, without the @, which commands, and mentions, in groups are matched against.
+	// It's only set if groups are enabled.
+	botUsername string
+	// stats caches the statistics shared by all users.
+	stats   *stats.Cache
+	metrics *botMetrics
 }
 
 func extractDomainApexZone(msg string) (string, error) {
+	_, apex, err := extractHost(msg)
+	return apex, err
+}
+
+// extractHost returns the lowercase host of the URL, or domain name, in msg, and its apex zone.
+func extractHost(msg string) (string, string, error) {
 	parsedURL, err := url.Parse(strings.TrimSpace(msg))
 	if nil != err {
-		return "", err
+		return "", "", err
 	}
 
 	domain := parsedURL.Hostname()
@@ -211,25 +771,29 @@ func extractDomainApexZone(msg string) (string, error) {
 		path := parsedURL.Path
 		parts := strings.SplitN(path, "/", 2)
 		if len(parts) < 1 {
-			return "", fmt.Errorf("could not extract domain from path '%s'", path)
+			return "", "", fmt.Errorf("could not extract domain from path '%s'", path)
 		}
 		domain = parts[0]
 	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
 
 	partsCount := strings.Count(domain, ".")
 	if partsCount > 5 {
-		return "", fmt.Errorf("subdomains depth exceeded maximum limit in '%s'", domain)
+		return "", "", fmt.Errorf("subdomains depth exceeded maximum limit in '%s'", domain)
 	}
 	if partsCount < 1 {
-		return "", fmt.Errorf("could not find domain apex zone and tld parts in '%s'", domain)
+		return "", "", fmt.Errorf("could not find domain apex zone and tld parts in '%s'", domain)
 	}
 	parts := strings.Split(domain, ".")
 	if len(parts) < 2 {
-		return "", fmt.Errorf("could not extract domain apex zone from '%s'", domain)
+		return "", "", fmt.Errorf("could not extract domain apex zone from '%s'", domain)
 	}
 	apex, tld := parts[partsCount-1], parts[partsCount]
+	if apex == "" || tld == "" {
+		return "", "", fmt.Errorf("could not extract domain apex zone from '%s'", domain)
+	}
 
-	return strings.ToLower(apex + "." + tld), nil
+	return domain, apex + "." + tld, nil
 }
 
 func (h *Handler) handleMessage(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -238,71 +802,149 @@ func (h *Handler) handleMessage(ctx context.Context, b *bot.Bot, update *models.
 	}
 
 	log := h.loggerFromUpdate(update)
+	h.submit(ctx, b, log, submission{
+		text:      update.Message.Text,
+		user:      update.Message.From,
+		messageID: update.Message.ID,
+		lang:      h.userLanguage(ctx, log, update.Message.From),
+		reply:     reply{chatID: update.Message.Chat.ID},
+	})
+}
 
-	chatID := update.Message.Chat.ID
-	userID := update.Message.From.ID
-	if canPass, err := h.rateLimiter.CanPass(ctx, userID); nil != err {
-		h.informSupport(ctx, b, err)
-		if errors.Is(err, db.ErrBusy) {
-			log.Error().Msg("got database is busy error on user rate limit check")
-			return
-		}
-		log.Error().Err(err).Msg("failed to check user rate limit")
-	} else if !canPass {
-		h.replyRateLimitExceeded(ctx, b, chatID)
-		return
-	}
+// submission is a domain name, or link, submitted in a private chat, or a group.
+type submission struct {
+	text string
+	user *models.User
+	// messageID is the message of the submission, which the success reply replies to.
+	messageID int
+	lang      i18n.Lang
+	reply     reply
+	// group is the settings of the group the submission is sent in, or nil in private chats.
+	group *model.Chats
+}
+
+// reply addresses the replies to a message.
+type reply struct {
+	chatID int64
+	// messageID is the message replied to, or zero for standalone messages.
+	messageID int
+	// silent suppresses the replies, in groups with silent mode on.
+	silent bool
+}
+
+func (h *Handler) submit(ctx context.Context, b *bot.Bot, log zerolog.Logger, sub submission) {
+	lang, r := sub.lang, sub.reply
+	userID := h.pseudonymizer.ID(sub.user.ID)
 
-	domain, err := extractDomainApexZone(update.Message.Text)
+	host, domain, err := extractHost(sub.text)
 	if nil != err {
 		log.
 			Debug().
 			Err(err).
 			Msg("failed to extract domain from message text")
-		h.replyInvalidDomain(ctx, b, chatID)
+		if !h.takeAttempt(ctx, b, log, sub, ratelimit.OutcomeFailed) {
+			return
+		}
+		h.metrics.countSubmission(submissionInvalid)
+		h.replyInvalidDomain(ctx, b, lang, r)
+		return
+	}
+	log = log.With().Str("domain", domain).Str("host", host).Logger()
+
+	if existing, err := h.store.FindHost(ctx, host); nil == err {
+		h.metrics.countSubmission(submissionDuplicate)
+		h.replyDuplicateDomain(ctx, b, lang, r, existing.CreatedTs)
+		return
+	} else if !errors.Is(err, db.ErrHostNotFound) {
+		h.metrics.countSubmission(submissionError)
+		if errors.Is(err, db.ErrBusy) {
+			h.replyInternalError(ctx, b, lang, r)
+			log.Error().Msg("got database is busy error on host lookup")
+			return
+		}
+		log.Error().Err(err).Msg("failed to lookup host from database")
+		h.replyInternalError(ctx, b, lang, r)
+		h.informSupport(ctx, b, err)
+		return
+	}
+
+	if !h.submissionThrottle.Allow() {
+		h.metrics.countSubmission(submissionRateLimited)
+		log.Warn().Msg("global submission throttle exceeded")
+		h.replyThrottled(ctx, b, lang, r)
 		return
 	}
-	log = log.With().Str("domain", domain).Logger()
 
-	if isResolvable, err := dns.IsDomainResolvable(ctx, domain, dns.WithRetries(3)); nil != err {
-		h.replyInvalidDomain(ctx, b, chatID)
-		log.Debug().Err(err).Msg("got error from dns resolver resolving domain")
+	// The outcome isn't known until the host is resolved, so the attempt is only taken afterwards, from the budget
+	// of its outcome, which keeps the budgets of successful, and failed, submissions independent.
+	// The host is resolved rather than the apex, which may not resolve, e.g. when only its subdomains are served.
+	isResolvable, err := dns.IsDomainResolvable(ctx, host, dns.WithRetries(3), dns.WithObserver(h.metrics.observeDNSLookup))
+	if nil != err || !isResolvable {
+		if nil != err {
+			log.Debug().Err(err).Msg("got error from dns resolver resolving domain")
+		} else {
+			log.Debug().Msg("domain is not resolvable")
+		}
+		if !h.takeAttempt(ctx, b, log, sub, ratelimit.OutcomeFailed) {
+			return
+		}
+		h.metrics.countSubmission(submissionUnresolvable)
+		h.replyInvalidDomain(ctx, b, lang, r)
 		return
-	} else if !isResolvable {
-		h.replyInvalidDomain(ctx, b, chatID)
-		log.Debug().Msg("domain is not resolvable")
+	}
+	if !h.takeAttempt(ctx, b, log, sub, ratelimit.OutcomeSucceeded) {
 		return
 	}
 
-	if err := db.InsertDomain(ctx, h.db, domain, userID); nil != err {
-		if errors.Is(err, db.ErrDuplicateDomain) {
-			h.replyDuplicateDomain(ctx, b, chatID)
+	domainInserted, err := h.store.InsertHost(ctx, host, domain, userID)
+	if nil != err {
+		h.cancelAttempt(ctx, b, log, sub, ratelimit.OutcomeSucceeded)
+		if errors.Is(err, db.ErrDuplicateHost) {
+			h.metrics.countSubmission(submissionDuplicate)
+			// The host was inserted by a concurrent submission since it was looked up.
+			existing, err := h.store.FindHost(ctx, host)
+			if nil != err {
+				log.Error().Err(err).Msg("failed to lookup host inserted by a concurrent submission")
+				h.replyInternalError(ctx, b, lang, r)
+				return
+			}
+			h.replyDuplicateDomain(ctx, b, lang, r, existing.CreatedTs)
 			return
 		}
+		h.metrics.countSubmission(submissionError)
 		if errors.Is(err, db.ErrBusy) {
-			h.replyInternalError(ctx, b, chatID)
-			log.Error().Msg("got database is busy error on domain insertion")
+			h.replyInternalError(ctx, b, lang, r)
+			log.Error().Msg("got database is busy error on host insertion")
 			return
 		}
-		log.Error().Err(err).Msg("failed to insert domain into database")
-		h.replyInternalError(ctx, b, chatID)
+		log.Error().Err(err).Msg("failed to insert host into database")
+		h.replyInternalError(ctx, b, lang, r)
 		h.informSupport(ctx, b, err)
 		return
 	}
+	h.metrics.countSubmission(submissionAccepted)
 
-	successMessageText := "`" + domain + "`"
+	successMessageText := h.text(lang, i18n.MsgDomainAdded, i18n.Params{"Host": host})
 	replyMsg := bot.SendMessageParams{
-		ChatID:           chatID,
-		ReplyToMessageID: update.Message.ID,
+		ChatID:           r.chatID,
+		ReplyToMessageID: sub.messageID,
 		Text:             successMessageText,
-		ParseMode:        ParseModeMarkdownV1,
+		ParseMode:        markup.MarkdownV2.ParseMode(),
+	}
+	// Only the submitter of the domain may pick its category, not submitters of its other hosts.
+	// Categories are only picked in private chats, as the keyboard would be pressed by other members of groups.
+	if domainInserted && nil == sub.group {
+		if keyboard := h.categoryKeyboard(ctx, log, domain); nil != keyboard {
+			successMessageText += "\n\n" + h.text(lang, i18n.MsgCategoryPrompt, nil)
+			replyMsg.Text = successMessageText
+			replyMsg.ReplyMarkup = keyboard
+		}
 	}
-	if _, err := b.SendMessage(ctx, &replyMsg); nil != err {
+	if _, err := h.sender.SendMessage(ctx, b, &replyMsg); nil != err {
 		log.
 			Error().
 			Err(err).
-			Dict("reply_message", zerolog.Dict().
-				Int64("chat_id", chatID).
+			Dict("reply_message", h.replyDict(r.chatID).
 				Str("text", successMessageText),
 			).
 			Msg("failed to send success reply message to user chat")
@@ -310,85 +952,183 @@ func (h *Handler) handleMessage(ctx context.Context, b *bot.Bot, update *models.
 	}
 }
 
-func (h *Handler) replyDuplicateDomain(ctx context.Context, b *bot.Bot, chatID int64) {
+// takeAttempt takes an attempt of the outcome from the budget of the user, and one from the budget of the group
+// of the submission, if any. It replies to the user, and returns false, if either budget is exhausted, in which case
+// nothing is taken.
+func (h *Handler) takeAttempt(ctx context.Context, b *bot.Bot, log zerolog.Logger, sub submission, outcome ratelimit.Outcome) bool {
+	userID := h.pseudonymizer.ID(sub.user.ID)
+	if !h.takeBudget(ctx, b, log, sub, userID, i18n.MsgRateLimitExceeded, outcome) {
+		return false
+	}
+	if nil != sub.group && !h.takeBudget(ctx, b, log, sub, pseudonym.ID(sub.group.TheChatID), i18n.MsgGroupRateLimitExceeded, ratelimit.OutcomeGroup) {
+		h.refundBudget(ctx, b, log, userID, outcome)
+		return false
+	}
+
+	return true
+}
+
+// takeBudget is takeAttempt for the budget of either the user, or the group, replying with msg if it's exhausted.
+func (h *Handler) takeBudget(ctx context.Context, b *bot.Bot, log zerolog.Logger, sub submission, id pseudonym.ID, msg i18n.MessageID, outcome ratelimit.Outcome) bool {
+	res, err := h.rateLimiter.Take(ctx, id, outcome)
+	if nil != err {
+		h.informSupport(ctx, b, err)
+		if errors.Is(err, db.ErrBusy) {
+			h.metrics.countSubmission(submissionError)
+			log.Error().Msg("got database is busy error on user rate limit check")
+			return false
+		}
+		log.Error().Err(err).Msg("failed to check user rate limit")
+		return true
+	}
+	if !res.Allowed {
+		h.metrics.countSubmission(submissionRateLimited)
+		h.replyRateLimitExceeded(ctx, b, sub.lang, sub.reply, msg, res.RetryAt)
+		return false
+	}
+
+	return true
+}
+
+// cancelAttempt gives back the attempt of the outcome taken for a submission which turns out not to count,
+// e.g. as it's a duplicate.
+func (h *Handler) cancelAttempt(ctx context.Context, b *bot.Bot, log zerolog.Logger, sub submission, outcome ratelimit.Outcome) {
+	h.refundBudget(ctx, b, log, h.pseudonymizer.ID(sub.user.ID), outcome)
+	if nil != sub.group {
+		h.refundBudget(ctx, b, log, pseudonym.ID(sub.group.TheChatID), ratelimit.OutcomeGroup)
+	}
+}
+
+func (h *Handler) refundBudget(ctx context.Context, b *bot.Bot, log zerolog.Logger, id pseudonym.ID, outcome ratelimit.Outcome) {
+	if err := h.rateLimiter.Refund(ctx, id, outcome); nil != err {
+		if errors.Is(err, db.ErrBusy) {
+			log.Error().Str("outcome", string(outcome)).Msg("got database is busy error on user rate limit refund")
+			return
+		}
+		log.Error().Err(err).Str("outcome", string(outcome)).Msg("failed to refund user rate limit attempt")
+		h.informSupport(ctx, b, err)
+	}
+}
+
+func (h *Handler) replyDuplicateDomain(ctx context.Context, b *bot.Bot, lang i18n.Lang, r reply, createdTs int64) {
+	if r.silent {
+		return
+	}
+	since := time.Unix(createdTs, 0).UTC().Format("2006-01-02")
 	msg := bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      "Domain is already registered.\n\nنام دامنه قبلا ثبت شده است.",
-		ParseMode: ParseModeMarkdownV1,
+		ChatID:           r.chatID,
+		ReplyToMessageID: r.messageID,
+		Text:             h.text(lang, i18n.MsgDuplicateDomain, i18n.Params{"Since": since}),
+		ParseMode:        markup.MarkdownV2.ParseMode(),
 	}
-	if _, sendErr := b.SendMessage(ctx, &msg); nil != sendErr {
+	if _, sendErr := h.sender.SendMessage(ctx, b, &msg); nil != sendErr {
 		h.log.
 			Error().
 			Err(sendErr).
-			Dict("reply_message", zerolog.Dict().
-				Int64("chat_id", chatID),
-			).
+			Dict("reply_message", h.replyDict(r.chatID)).
 			Msg("failed to send duplicate domain reply message to user chat")
 		return
 	}
 }
 
-func (h *Handler) replyInternalError(ctx context.Context, b *bot.Bot, chatID int64) {
+func (h *Handler) replyInternalError(ctx context.Context, b *bot.Bot, lang i18n.Lang, r reply) {
+	if r.silent {
+		return
+	}
 	msg := bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      "Internal error occurred. Retry, and reach support if the problem persists.\n\nخطای داخلی رخ داده است. در صورتی که پس از تلاش مجدد مشکل برطرف نشد، به پشتیبانی پیام دهید.",
-		ParseMode: ParseModeMarkdownV1,
+		ChatID:           r.chatID,
+		ReplyToMessageID: r.messageID,
+		Text:             h.text(lang, i18n.MsgInternalError, nil),
+		ParseMode:        markup.MarkdownV2.ParseMode(),
 	}
-	if _, sendErr := b.SendMessage(ctx, &msg); nil != sendErr {
+	if _, sendErr := h.sender.SendMessage(ctx, b, &msg); nil != sendErr {
 		h.log.
 			Error().
 			Err(sendErr).
-			Dict("reply_message", zerolog.Dict().
-				Int64("chat_id", chatID),
-			).
+			Dict("reply_message", h.replyDict(r.chatID)).
 			Msg("failed to send internal error reply message to user chat")
 		return
 	}
 }
-func (h *Handler) replyRateLimitExceeded(ctx context.Context, b *bot.Bot, chatID int64) {
+
+// replyRateLimitExceeded replies with the message id, which is either the rate limit exceeded message of users, or the one of groups.
+func (h *Handler) replyRateLimitExceeded(ctx context.Context, b *bot.Bot, lang i18n.Lang, r reply, id i18n.MessageID, retryAt time.Time) {
+	if r.silent {
+		return
+	}
+	reset := retryAt.UTC().Format("2006-01-02 15:04") + " UTC"
 	msg := bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      "Rate limit exceeded. Retry in the next 24 hours.\n\nتعداد درخواست‌های شما بیشتر از حد مجاز هستند. می‌توانید مجددا بعد از ۲۴ ساعت تلاش کنید.",
-		ParseMode: ParseModeMarkdownV1,
+		ChatID:           r.chatID,
+		ReplyToMessageID: r.messageID,
+		Text:             h.text(lang, id, i18n.Params{"RetryAt": reset}),
+		ParseMode:        markup.MarkdownV2.ParseMode(),
 	}
-	if _, sendErr := b.SendMessage(ctx, &msg); nil != sendErr {
+	if _, sendErr := h.sender.SendMessage(ctx, b, &msg); nil != sendErr {
 		h.log.
 			Error().
 			Err(sendErr).
-			Dict("reply_message", zerolog.Dict().
-				Int64("chat_id", chatID),
-			).
+			Dict("reply_message", h.replyDict(r.chatID)).
 			Msg("failed to send rate limit exceeded reply message to user chat")
 		return
 	}
 }
 
-func (h *Handler) replyInvalidDomain(ctx context.Context, b *bot.Bot, chatID int64) {
+func (h *Handler) replyThrottled(ctx context.Context, b *bot.Bot, lang i18n.Lang, r reply) {
+	if r.silent {
+		return
+	}
 	msg := bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      "Invalid domain name. It should be a simple domain name like: `git.ir`.\n\nنام دامنه نامعتبر است. ورودی باید یک نام دامنه مثل `git.ir` باشد.",
-		ParseMode: ParseModeMarkdownV1,
+		ChatID:           r.chatID,
+		ReplyToMessageID: r.messageID,
+		Text:             h.text(lang, i18n.MsgThrottled, nil),
+		ParseMode:        markup.MarkdownV2.ParseMode(),
 	}
-	if _, sendErr := b.SendMessage(ctx, &msg); nil != sendErr {
+	if _, sendErr := h.sender.SendMessage(ctx, b, &msg); nil != sendErr {
 		h.log.
 			Error().
 			Err(sendErr).
-			Dict("reply_message", zerolog.Dict().
-				Int64("chat_id", chatID),
-			).
+			Dict("reply_message", h.replyDict(r.chatID)).
+			Msg("failed to send throttled reply message to user chat")
+		return
+	}
+}
+
+func (h *Handler) replyInvalidDomain(ctx context.Context, b *bot.Bot, lang i18n.Lang, r reply) {
+	if r.silent {
+		return
+	}
+	msg := bot.SendMessageParams{
+		ChatID:           r.chatID,
+		ReplyToMessageID: r.messageID,
+		Text:             h.text(lang, i18n.MsgInvalidDomain, nil),
+		ParseMode:        markup.MarkdownV2.ParseMode(),
+	}
+	if _, sendErr := h.sender.SendMessage(ctx, b, &msg); nil != sendErr {
+		h.log.
+			Error().
+			Err(sendErr).
+			Dict("reply_message", h.replyDict(r.chatID)).
 			Msg("failed to send invalid domain name reply message to user chat")
 		return
 	}
 }
 
+// supportErrorMaxLength is the maximum length of errors in support alerts, leaving room for the header.
+const supportErrorMaxLength = markup.MaxMessageLength - 128
+
+// supportAlertText returns the alert of err, which is HTML, as errors may have any text, e.g. of submitted domains.
+func supportAlertText(err error) string {
+	return "🚨 An unexpected error occurred. Please check the logs...\n\n" + markup.HTML.Pre(markup.Truncate(err.Error(), supportErrorMaxLength))
+}
+
 func (h *Handler) informSupport(ctx context.Context, b *bot.Bot, err error) {
 	chatID := h.publishChatID
 	msg := bot.SendMessageParams{
 		ChatID:    chatID,
-		Text:      "🚨 An unexpected error occurred. Please check the logs...\n\n```\n" + err.Error() + "```",
-		ParseMode: ParseModeMarkdownV1,
+		Text:      supportAlertText(err),
+		ParseMode: markup.HTML.ParseMode(),
 	}
-	if _, sendErr := b.SendMessage(ctx, &msg); nil != sendErr {
+	if _, sendErr := h.sender.SendMessage(ctx, b, &msg); nil != sendErr {
 		h.log.
 			Error().
 			Err(sendErr).
@@ -409,50 +1149,47 @@ func (h *Handler) handleStartCommand(ctx context.Context, b *bot.Bot, update *mo
 
 	replyText := strings.Join(
 		[]string{
-			fmt.Sprintf("Compiled At: `%s`", bot.EscapeMarkdown(AppCompileTime)),
-			fmt.Sprintf("Version: `%s`", bot.EscapeMarkdown(AppVersion)),
+			"Compiled At: " + markup.MarkdownV2.Code(AppCompileTime),
+			"Version: " + markup.MarkdownV2.Code(AppVersion),
 		},
 		"\n",
 	)
-	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+	if _, err := h.sender.SendMessage(ctx, b, &bot.SendMessageParams{
 		ChatID:    update.Message.Chat.ID,
 		Text:      replyText,
-		ParseMode: models.ParseModeMarkdown,
+		ParseMode: markup.MarkdownV2.ParseMode(),
 	}); nil != err {
 		log.Error().Err(err).Str("reply_text", replyText).Msg("failed to send start command success reply message")
 	}
 }
 
-//go:embed info.txt
-var infoCommandReplyMessageText string
-
 func (h *Handler) handleInfoCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if shouldDiscard(update) {
 		return
 	}
 	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
 
-	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: update.Message.Chat.ID,
-		Text:   infoCommandReplyMessageText,
+	if _, err := h.sender.SendMessage(ctx, b, &bot.SendMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		Text:      h.text(lang, i18n.MsgInfo, nil),
+		ParseMode: markup.MarkdownV2.ParseMode(),
 	}); nil != err {
 		log.Error().Err(err).Msg("failed to send info command success reply message")
 	}
 }
 
-//go:embed help.txt
-var helpCommandReplyMessageText string
-
 func (h *Handler) handleHelpCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if shouldDiscard(update) {
 		return
 	}
 	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
 
-	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+	if _, err := h.sender.SendMessage(ctx, b, &bot.SendMessageParams{
 		ChatID:    update.Message.Chat.ID,
-		Text:      helpCommandReplyMessageText,
-		ParseMode: ParseModeMarkdownV1,
+		Text:      h.text(lang, i18n.MsgHelp, nil),
+		ParseMode: markup.MarkdownV2.ParseMode(),
 	}); nil != err {
 		log.Error().Err(err).Msg("failed to send help command success reply message")
 	}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+)
+
+func FuzzSupportAlertText(f *testing.F) {
+	f.Add("db: failed to insert host: UNIQUE constraint failed: hosts.host")
+	f.Add(`telegram: Bad Request: can't parse entities: Character '.' is reserved and must be escaped`)
+	f.Add("<pre>&amp;</pre> \"'")
+	f.Add("invalid \xff utf-8")
+	f.Add(strings.Repeat("<&>", markup.MaxMessageLength))
+	f.Fuzz(func(t *testing.T, s string) {
+		text := supportAlertText(errors.New(s))
+		if !utf8.ValidString(text) {
+			t.Fatalf("supportAlertText(%q) is invalid UTF-8", s)
+		}
+		head, body, ok := strings.Cut(text, "<pre>")
+		if !ok || strings.ContainsAny(head, "<>&") {
+			t.Fatalf("supportAlertText(%q) = %q, want a plain header followed by a pre tag", s, text)
+		}
+		inner, ok := strings.CutSuffix(body, "</pre>")
+		if !ok || strings.ContainsAny(inner, "<>") {
+			t.Fatalf("supportAlertText(%q) = %q, want the error escaped in a single pre tag", s, text)
+		}
+		// The length of a message is counted after its entities are parsed.
+		if n := utf8.RuneCountInString(head + html.UnescapeString(inner)); n > markup.MaxMessageLength {
+			t.Fatalf("supportAlertText(%q) has %d characters, want at most %d", s, n, markup.MaxMessageLength)
+		}
+		want := strings.ToValidUTF8(markup.Truncate(s, supportErrorMaxLength), "�")
+		if got := html.UnescapeString(inner); got != want {
+			t.Fatalf("supportAlertText(%q) has error %q, want %q", s, got, want)
+		}
+	})
+}
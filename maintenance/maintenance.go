@@ -0,0 +1,215 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+)
+
+type Task string
+
+const (
+	TaskCheckpoint        Task = "wal_checkpoint"
+	TaskOptimize          Task = "optimize"
+	TaskIncrementalVacuum Task = "incremental_vacuum"
+	TaskIntegrityCheck    Task = "integrity_check"
+)
+
+var ErrCheckpointBusy = errors.New("wal checkpoint could not complete due to concurrent readers or writers")
+
+type Config struct {
+	CheckpointInterval        time.Duration
+	OptimizeInterval          time.Duration
+	IncrementalVacuumInterval time.Duration
+	IntegrityCheckInterval    time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		CheckpointInterval:        5 * time.Minute,
+		OptimizeInterval:          time.Hour,
+		IncrementalVacuumInterval: 6 * time.Hour,
+		IntegrityCheckInterval:    24 * time.Hour,
+	}
+}
+
+type Status struct {
+	Task          Task
+	LastRunAt     time.Time
+	LastSuccessAt time.Time
+	LastDuration  time.Duration
+	LastErr       error
+}
+
+// Scheduler runs database maintenance tasks periodically, since automatic
+// WAL checkpoints are disabled, and the WAL file size is not limited.
+type Scheduler struct {
+	db    *sql.DB
+	tasks map[Task]time.Duration
+
+	mu     sync.RWMutex
+	status map[Task]Status
+}
+
+func New(db *sql.DB, cfg Config) *Scheduler {
+	return &Scheduler{
+		db: db,
+		tasks: map[Task]time.Duration{
+			TaskCheckpoint:        cfg.CheckpointInterval,
+			TaskOptimize:          cfg.OptimizeInterval,
+			TaskIncrementalVacuum: cfg.IncrementalVacuumInterval,
+			TaskIntegrityCheck:    cfg.IntegrityCheckInterval,
+		},
+		status: map[Task]Status{},
+	}
+}
+
+// EnableIncrementalVacuum switches the database to incremental auto vacuum mode if it's not already.
+// It's required for incremental vacuum to free pages, and rebuilds the whole database once.
+func EnableIncrementalVacuum(ctx context.Context, db *sql.DB) error {
+	var mode int
+	if err := db.QueryRowContext(ctx, "PRAGMA auto_vacuum;").Scan(&mode); nil != err {
+		return fmt.Errorf("failed to query auto vacuum mode: %v", err)
+	}
+	const incremental = 2
+	if mode == incremental {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL;"); nil != err {
+		return fmt.Errorf("failed to set auto vacuum mode: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM;"); nil != err {
+		return fmt.Errorf("failed to vacuum database after changing auto vacuum mode: %v", err)
+	}
+
+	return nil
+}
+
+// Run runs every task on its own interval until ctx is done. The status of every run is passed to onResult.
+func (s *Scheduler) Run(ctx context.Context, onResult func(Status)) {
+	var wg sync.WaitGroup
+	for task, interval := range s.tasks {
+		if interval <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(task Task, interval time.Duration) {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					s.RunTask(ctx, task)
+					if ctx.Err() != nil {
+						return
+					}
+					onResult(s.taskStatus(task))
+				}
+			}
+		}(task, interval)
+	}
+	wg.Wait()
+}
+
+// RunTask runs a single task immediately, and records its status.
+func (s *Scheduler) RunTask(ctx context.Context, task Task) error {
+	startedAt := time.Now().UTC()
+	var err error
+	switch task {
+	case TaskCheckpoint:
+		err = s.checkpoint(ctx)
+	case TaskOptimize:
+		_, err = s.db.ExecContext(ctx, "PRAGMA optimize;")
+	case TaskIncrementalVacuum:
+		_, err = s.db.ExecContext(ctx, "PRAGMA incremental_vacuum;")
+	case TaskIntegrityCheck:
+		err = s.integrityCheck(ctx)
+	default:
+		err = fmt.Errorf("unknown maintenance task '%s'", task)
+	}
+	if nil != err {
+		err = fmt.Errorf("maintenance: %s failed: %w", task, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.status[task]
+	st.Task = task
+	st.LastRunAt = startedAt
+	st.LastDuration = time.Since(startedAt)
+	st.LastErr = err
+	if nil == err {
+		st.LastSuccessAt = startedAt
+	}
+	s.status[task] = st
+
+	return err
+}
+
+func (s *Scheduler) taskStatus(task Task) Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status[task]
+}
+
+// Status returns the last run status of every task.
+func (s *Scheduler) Status() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := make([]Status, 0, len(s.status))
+	for _, task := range []Task{TaskCheckpoint, TaskOptimize, TaskIncrementalVacuum, TaskIntegrityCheck} {
+		if st, ok := s.status[task]; ok {
+			res = append(res, st)
+		}
+	}
+	return res
+}
+
+func (s *Scheduler) checkpoint(ctx context.Context) error {
+	var busy, logFrames, checkpointedFrames int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE);").Scan(&busy, &logFrames, &checkpointedFrames); nil != err {
+		return err
+	}
+	if busy != 0 {
+		return ErrCheckpointBusy
+	}
+	return nil
+}
+
+func (s *Scheduler) integrityCheck(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, "PRAGMA integrity_check;")
+	if nil != err {
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); nil != err {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); nil != err {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", db.ErrCorrupt, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package markup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markdownSpecial are the characters that must be escaped outside of code entities in MarkdownV2.
+const markdownSpecial = "\\_*[]()~`>#+-=|{}.!"
+
+// markdownCodeSpecial are the characters that must be escaped inside of code entities in MarkdownV2.
+const markdownCodeSpecial = "\\`"
+
+func escapeMarkdown(s string, special string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// ValidateMarkdownV2 returns an error if s has unescaped special characters, or unterminated entities, which Telegram rejects.
+// Links are the only entities with brackets, or parentheses.
+func ValidateMarkdownV2(s string) error {
+	rs := []rune(s)
+	open := map[rune]int{}
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; {
+		case r == '\\':
+			i++
+		case r == '`' && isPreDelimiter(rs, i):
+			end := -1
+			for j := i + 3; j < len(rs); j++ {
+				if rs[j] == '\\' {
+					j++
+				} else if isPreDelimiter(rs, j) {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return fmt.Errorf("unterminated pre-formatted code at offset %d", i)
+			}
+			i = end + 2
+		case r == '`':
+			end := indexUnescaped(rs, i+1, '`')
+			if end < 0 {
+				return fmt.Errorf("unterminated code at offset %d", i)
+			}
+			i = end
+		case r == '*' || r == '_' || r == '~' || r == '|':
+			open[r]++
+		case r == '[':
+			end := indexUnescaped(rs, i+1, ']')
+			if end < 0 || end+1 >= len(rs) || rs[end+1] != '(' {
+				return fmt.Errorf("unescaped '[' at offset %d", i)
+			}
+			urlEnd := indexUnescaped(rs, end+2, ')')
+			if urlEnd < 0 {
+				return fmt.Errorf("unterminated link at offset %d", i)
+			}
+			if err := ValidateMarkdownV2(string(rs[i+1 : end])); nil != err {
+				return fmt.Errorf("invalid link text at offset %d: %v", i, err)
+			}
+			i = urlEnd
+		case strings.ContainsRune(markdownSpecial, r):
+			return fmt.Errorf("unescaped '%c' at offset %d", r, i)
+		}
+	}
+	for r, n := range open {
+		if n%2 != 0 {
+			return fmt.Errorf("unterminated '%c' entity", r)
+		}
+	}
+	return nil
+}
+
+// StripMarkdownV2 returns the text of s without its entities, which must be valid MarkdownV2,
+// e.g. for callback query answers that don't support formatting.
+func StripMarkdownV2(s string) string {
+	rs := []rune(s)
+	var sb strings.Builder
+	inCode := false
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; {
+		case r == '\\':
+			if i+1 < len(rs) {
+				i++
+				sb.WriteRune(rs[i])
+			}
+		case r == '`':
+			if isPreDelimiter(rs, i) {
+				i += 2
+			}
+			inCode = !inCode
+		case inCode:
+			sb.WriteRune(r)
+		case r == '*' || r == '_' || r == '~' || r == '|' || r == '[':
+		case r == ']':
+			if i+1 < len(rs) && rs[i+1] == '(' {
+				if end := indexUnescaped(rs, i+2, ')'); end >= 0 {
+					i = end
+				}
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// indexUnescaped returns the index of the first unescaped r in rs from start, or -1.
+func indexUnescaped(rs []rune, start int, r rune) int {
+	for i := start; i < len(rs); i++ {
+		switch rs[i] {
+		case '\\':
+			i++
+		case r:
+			return i
+		}
+	}
+	return -1
+}
+
+func isPreDelimiter(rs []rune, i int) bool {
+	return i+2 < len(rs) && rs[i] == '`' && rs[i+1] == '`' && rs[i+2] == '`'
+}
@@ -0,0 +1,91 @@
+// Package markup formats Telegram messages, escaping all text it's given, so that messages never fail to parse.
+package markup
+
+import (
+	"html"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// MaxMessageLength is the maximum number of characters of a message text, after entities are parsed.
+const MaxMessageLength = 4096
+
+// Renderer formats text in the syntax of a Telegram parse mode.
+type Renderer interface {
+	ParseMode() models.ParseMode
+	// Escape returns s displayed as is.
+	Escape(s string) string
+	// Code returns s as inline code.
+	Code(s string) string
+	// Pre returns s as a pre-formatted code block.
+	Pre(s string) string
+	Bold(s string) string
+}
+
+var (
+	MarkdownV2 Renderer = markdownV2{}
+	HTML       Renderer = htmlRenderer{}
+)
+
+type markdownV2 struct{}
+
+func (markdownV2) ParseMode() models.ParseMode {
+	return models.ParseModeMarkdown
+}
+
+func (markdownV2) Escape(s string) string {
+	return escapeMarkdown(s, markdownSpecial)
+}
+
+func (markdownV2) Code(s string) string {
+	return "`" + escapeMarkdown(s, markdownCodeSpecial) + "`"
+}
+
+func (markdownV2) Pre(s string) string {
+	return "```\n" + escapeMarkdown(s, markdownCodeSpecial) + "\n```"
+}
+
+func (markdownV2) Bold(s string) string {
+	return "*" + escapeMarkdown(s, markdownSpecial) + "*"
+}
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) ParseMode() models.ParseMode {
+	return models.ParseModeHTML
+}
+
+func (htmlRenderer) Escape(s string) string {
+	return escapeHTML(s)
+}
+
+func (htmlRenderer) Code(s string) string {
+	return "<code>" + escapeHTML(s) + "</code>"
+}
+
+func (htmlRenderer) Pre(s string) string {
+	return "<pre>" + escapeHTML(s) + "</pre>"
+}
+
+func (htmlRenderer) Bold(s string) string {
+	return "<b>" + escapeHTML(s) + "</b>"
+}
+
+// escapeHTML also replaces invalid UTF-8, which Telegram rejects, as escapeMarkdown does.
+func escapeHTML(s string) string {
+	return html.EscapeString(strings.ToValidUTF8(s, "\uFFFD"))
+}
+
+// Truncate returns s cut to at most n characters, ending with an ellipsis if it's cut. It must be called before escaping,
+// so that escape sequences aren't split.
+func Truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(rs[:n-1])) + "…"
+}
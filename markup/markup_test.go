@@ -0,0 +1,135 @@
+package markup
+
+import (
+	"html"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// seeds are texts of errors, and domains, which have every special character of both parse modes.
+var seeds = []string{
+	"",
+	"example.ir",
+	"sub-domain.example.ir",
+	"db: failed to insert host: UNIQUE constraint failed: hosts.host",
+	`resp: unexpected reply to MGET: [1 "2" <nil>]`,
+	"_*[]()~`>#+-=|{}.!\\",
+	"```code``` and `inline` and \\`escaped\\`",
+	"[link](https://example.ir/?a=1&b=2)",
+	"<b>bold</b> & <i>italic</i> \"quoted\" 'single'",
+	"line\nbreak\r\nand\ttab",
+	"invalid \xff\xfe utf-8",
+	"دامنه‌ی نامعتبر: مثال.ایران",
+	"emoji 🚨 and combining é",
+	"trailing backslash\\",
+}
+
+// decode returns s as escapeMarkdown, and Truncate, see it, i.e. with every invalid byte replaced by U+FFFD.
+func decode(s string) string {
+	return string([]rune(s))
+}
+
+func FuzzEscape(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		out := MarkdownV2.Escape(s)
+		if err := ValidateMarkdownV2(out); nil != err {
+			t.Fatalf("Escape(%q) = %q, which is invalid: %v", s, out, err)
+		}
+		if got := StripMarkdownV2(out); got != decode(s) {
+			t.Fatalf("StripMarkdownV2(Escape(%q)) = %q, want the text as is", s, got)
+		}
+		bold := MarkdownV2.Bold(s)
+		if err := ValidateMarkdownV2(bold); nil != err {
+			t.Fatalf("Bold(%q) = %q, which is invalid: %v", s, bold, err)
+		}
+	})
+}
+
+func FuzzCode(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		out := MarkdownV2.Code(s)
+		if err := ValidateMarkdownV2(out); nil != err {
+			t.Fatalf("Code(%q) = %q, which is invalid: %v", s, out, err)
+		}
+		if got := StripMarkdownV2(out); got != decode(s) {
+			t.Fatalf("StripMarkdownV2(Code(%q)) = %q, want the text as is", s, got)
+		}
+		// Code is embedded in other text, e.g. domains in replies.
+		if err := ValidateMarkdownV2(MarkdownV2.Escape("Domain "+s+": ") + out + MarkdownV2.Escape(".")); nil != err {
+			t.Fatalf("Code(%q) in escaped text is invalid: %v", s, err)
+		}
+	})
+}
+
+func FuzzPre(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		out := MarkdownV2.Pre(s)
+		if err := ValidateMarkdownV2(out); nil != err {
+			t.Fatalf("Pre(%q) = %q, which is invalid: %v", s, out, err)
+		}
+		if got := StripMarkdownV2(out); got != "\n"+decode(s)+"\n" {
+			t.Fatalf("StripMarkdownV2(Pre(%q)) = %q, want the text as is", s, got)
+		}
+	})
+}
+
+func FuzzHTMLPre(f *testing.F) {
+	for _, s := range seeds {
+		f.Add(s, 10)
+		f.Add(s, MaxMessageLength)
+	}
+	f.Fuzz(func(t *testing.T, s string, n int) {
+		if n < 1 || n > MaxMessageLength {
+			return
+		}
+		truncated := Truncate(s, n)
+		if utf8.RuneCountInString(truncated) > n {
+			t.Fatalf("Truncate(%q, %d) = %q, which is longer", s, n, truncated)
+		}
+		out := HTML.Pre(truncated)
+		if !utf8.ValidString(out) {
+			t.Fatalf("Pre(%q) = %q, which is invalid UTF-8", truncated, out)
+		}
+		inner, ok := strings.CutPrefix(out, "<pre>")
+		if ok {
+			inner, ok = strings.CutSuffix(inner, "</pre>")
+		}
+		if !ok {
+			t.Fatalf("Pre(%q) = %q, want it enclosed in a pre tag", truncated, out)
+		}
+		if strings.ContainsAny(inner, "<>") {
+			t.Fatalf("Pre(%q) = %q, which has unescaped tags", truncated, out)
+		}
+		// Telegram supports the named entities &lt; &gt; &amp; &quot;, and every numerical one.
+		for rest := inner; ; {
+			i := strings.IndexByte(rest, '&')
+			if i < 0 {
+				break
+			}
+			rest = rest[i:]
+			end := strings.IndexByte(rest, ';')
+			if end < 0 {
+				t.Fatalf("Pre(%q) = %q, which has an unterminated entity", truncated, out)
+			}
+			switch entity := rest[:end+1]; {
+			case entity == "&lt;", entity == "&gt;", entity == "&amp;", entity == "&quot;", strings.HasPrefix(entity, "&#"):
+			default:
+				t.Fatalf("Pre(%q) = %q, which has unsupported entity %s", truncated, out, entity)
+			}
+			rest = rest[end+1:]
+		}
+		if got, want := html.UnescapeString(inner), strings.ToValidUTF8(truncated, "�"); got != want {
+			t.Fatalf("Pre(%q) unescapes to %q, want %q", truncated, got, want)
+		}
+	})
+}
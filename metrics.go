@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/metrics"
+)
+
+// submissionOutcome is how a submission was handled, which, unlike the outcomes of rate limiting, tells invalid,
+// and unresolvable, domains apart.
+type submissionOutcome string
+
+const (
+	submissionAccepted     submissionOutcome = "accepted"
+	submissionDuplicate    submissionOutcome = "duplicate"
+	submissionInvalid      submissionOutcome = "invalid"
+	submissionUnresolvable submissionOutcome = "unresolvable"
+	submissionRateLimited  submissionOutcome = "rate_limited"
+	// submissionError is a submission which failed due to the database, or the rate limiter.
+	submissionError submissionOutcome = "error"
+)
+
+// botMetrics are always recorded, and are only exposed if the metrics listener is enabled.
+type botMetrics struct {
+	registry    *metrics.Registry
+	updates     *metrics.Counter
+	submissions *metrics.Counter
+	dnsLookup   *metrics.Histogram
+	dbQuery     *metrics.Histogram
+	apiErrors   *metrics.Counter
+}
+
+func newBotMetrics() *botMetrics {
+	r := metrics.NewRegistry()
+	return &botMetrics{
+		registry:    r,
+		updates:     r.Counter("iran_domains_bot_updates_total", "Telegram updates received, by type.", "type"),
+		submissions: r.Counter("iran_domains_bot_submissions_total", "Domain submissions, by outcome.", "outcome"),
+		dnsLookup:   r.Histogram("iran_domains_bot_dns_lookup_duration_seconds", "Latency of DNS lookups of submitted hosts, by upstream server.", metrics.DefBuckets, "upstream"),
+		dbQuery:     r.Histogram("iran_domains_bot_db_query_duration_seconds", "Latency of database queries, by operation.", metrics.DefBuckets, "operation"),
+		apiErrors:   r.Counter("iran_domains_bot_telegram_api_errors_total", "Failed Telegram Bot API requests, by method, and HTTP status code, or error if no response was received.", "method", "code"),
+	}
+}
+
+func (m *botMetrics) countSubmission(outcome submissionOutcome) {
+	m.submissions.Inc(string(outcome))
+}
+
+func (m *botMetrics) observeDNSLookup(upstream string, took time.Duration) {
+	m.dnsLookup.Observe(took.Seconds(), upstream)
+}
+
+func (m *botMetrics) observeDBQuery(op string, took time.Duration) {
+	m.dbQuery.Observe(took.Seconds(), op)
+}
+
+// middleware counts every update, before it's handled.
+func (m *botMetrics) middleware(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		m.updates.Inc(updateType(update))
+		next(ctx, b, update)
+	}
+}
+
+func updateType(update *models.Update) string {
+	switch {
+	case nil != update.Message:
+		return "message"
+	case nil != update.EditedMessage:
+		return "edited_message"
+	case nil != update.ChannelPost:
+		return "channel_post"
+	case nil != update.EditedChannelPost:
+		return "edited_channel_post"
+	case nil != update.InlineQuery:
+		return "inline_query"
+	case nil != update.ChosenInlineResult:
+		return "chosen_inline_result"
+	case nil != update.CallbackQuery:
+		return "callback_query"
+	case nil != update.ShippingQuery:
+		return "shipping_query"
+	case nil != update.PreCheckoutQuery:
+		return "pre_checkout_query"
+	case nil != update.Poll:
+		return "poll"
+	case nil != update.PollAnswer:
+		return "poll_answer"
+	case nil != update.MyChatMember:
+		return "my_chat_member"
+	case nil != update.ChatMember:
+		return "chat_member"
+	case nil != update.ChatJoinRequest:
+		return "chat_join_request"
+	default:
+		return "unknown"
+	}
+}
+
+// apiClient counts the failed requests of the bot to the Telegram Bot API, and records the successful polls of updates.
+type apiClient struct {
+	client bot.HttpClient
+	errors *metrics.Counter
+	polls  *pollTracker
+}
+
+func (c apiClient) Do(req *http.Request) (*http.Response, error) {
+	// The path is /bot<token>/<method>, and the token must never be exposed.
+	method := path.Base(req.URL.Path)
+	resp, err := c.client.Do(req)
+	if nil != err {
+		// Long polling requests are canceled on shutdown, which isn't a failure of the API.
+		if !errors.Is(err, context.Canceled) {
+			c.errors.Inc(method, "error")
+		}
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.errors.Inc(method, strconv.Itoa(resp.StatusCode))
+	} else if method == "getUpdates" {
+		c.polls.polled()
+	}
+	return resp, nil
+}
+
+// metricsListenAddrFromEnv returns the address of the metrics listener, and whether it's enabled.
+func metricsListenAddrFromEnv() (string, bool, error) {
+	addr, ok := os.LookupEnv(EnvKeyMetricsListenAddr)
+	if !ok || addr == "" {
+		return "", false, nil
+	}
+	if _, _, err := net.SplitHostPort(addr); nil != err {
+		return "", false, fmt.Errorf("'%s' must be a host and port, e.g. 127.0.0.1:9090: %v", EnvKeyMetricsListenAddr, err)
+	}
+
+	return addr, true, nil
+}
+
+// serveMetrics listens on addr, and serves handler until ctx is done.
+// It returns once it's listening, so that the bot doesn't start if the address is taken.
+func serveMetrics(ctx context.Context, log zerolog.Logger, addr string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", addr)
+	if nil != err {
+		return fmt.Errorf("metrics: failed to listen on '%s': %v", addr, err)
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); nil != err {
+			log.Error().Err(err).Msg("failed to shut down metrics listener")
+		}
+	}()
+	go func() {
+		if err := server.Serve(listener); nil != err && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("metrics listener failed")
+		}
+	}()
+	log.Info().Str("addr", listener.Addr().String()).Msg("serving metrics, and health checks")
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	stdlog "log"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/pressly/goose/v3"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/migration"
+	pgmigration "github.com/z4x7k/iran-domains-tg-bot/db/postgres/migration"
+	"github.com/z4x7k/iran-domains-tg-bot/maintenance"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+const migrationsDir = "scripts"
+
+// setupMigrations configures goose for the dialect, and the embedded migrations, of the database.
+func setupMigrations(isPostgres bool, logger goose.Logger) error {
+	goose.SetLogger(logger)
+	goose.SetTableName("migrations")
+	dialect, fsys := "sqlite3", fs.FS(migration.FS)
+	if isPostgres {
+		dialect, fsys = "postgres", pgmigration.FS
+	}
+	goose.SetBaseFS(fsys)
+	if err := goose.SetDialect(dialect); nil != err {
+		return fmt.Errorf("db: failed to set goose dialect to %s: %v", dialect, err)
+	}
+	migration.SetDialect(dialect)
+
+	return nil
+}
+
+func latestMigrationVersion() (int64, error) {
+	migrations, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
+	if nil != err {
+		return 0, fmt.Errorf("db: failed to collect embedded migrations: %v", err)
+	}
+	last, err := migrations.Last()
+	if nil != err {
+		return 0, fmt.Errorf("db: failed to find latest embedded migration: %v", err)
+	}
+
+	return last.Version, nil
+}
+
+// checkSchemaVersion refuses databases with a schema newer than the latest migration of the binary,
+// which were migrated by a newer release, and ones with pending migrations.
+func checkSchemaVersion(ctx context.Context, dbConn *sql.DB) error {
+	current, err := goose.GetDBVersionContext(ctx, dbConn)
+	if nil != err {
+		return fmt.Errorf("db: failed to get database schema version: %v", err)
+	}
+	latest, err := latestMigrationVersion()
+	if nil != err {
+		return err
+	}
+	if current > latest {
+		return fmt.Errorf("db: database schema version %d is newer than the latest migration %d of this binary. upgrade the binary, or roll back the database with the newer one", current, latest)
+	}
+	if current < latest {
+		return fmt.Errorf("db: database schema version %d is behind the latest migration %d. run '%s %s' first", current, latest, CLIMigrateCommandName, CLIMigrateUpCommandName)
+	}
+
+	return nil
+}
+
+func migrateFlags() []cli.Flag {
+	return []cli.Flag{envFileFlag(), dbFileFlag()}
+}
+
+// migrateAction runs fn against the database with goose configured to print to the standard output.
+// sqlite databases are switched to incremental auto vacuum mode first, which rebuilds them once.
+func migrateAction(log zerolog.Logger, fn func(ctx context.Context, cliCtx *cli.Context, dbConn *sql.DB) error) func(*cli.Context) error {
+	return func(cliCtx *cli.Context) error {
+		ctx, cancel := signal.NotifyContext(cliCtx.Context, os.Interrupt)
+		defer cancel()
+
+		if err := loadEnv(log, cliCtx); nil != err {
+			return err
+		}
+		// The pseudonymization Go migration requires the key, while other migrations don't.
+		if key, ok := os.LookupEnv(EnvKeyPseudonymizationKey); ok && key != "" {
+			pseudonymizer, err := pseudonym.NewFromHex(key)
+			if nil != err {
+				return fmt.Errorf("env: invalid '%s': %v", EnvKeyPseudonymizationKey, err)
+			}
+			migration.SetPseudonymizer(pseudonymizer)
+		}
+
+		dbConn, isPostgres, closeDB, err := openDatabaseFromEnv(ctx, log, cliCtx)
+		if nil != err {
+			return err
+		}
+		defer closeDB()
+		if !isPostgres {
+			if err := maintenance.EnableIncrementalVacuum(ctx, dbConn); nil != err {
+				return fmt.Errorf("db: %v", err)
+			}
+		}
+
+		if err := setupMigrations(isPostgres, stdlog.New(os.Stdout, "", 0)); nil != err {
+			return err
+		}
+		if err := fn(ctx, cliCtx, dbConn); nil != err {
+			return fmt.Errorf("db: %v", err)
+		}
+
+		return nil
+	}
+}
+
+func migrateStatus(ctx context.Context, _ *cli.Context, dbConn *sql.DB) error {
+	return goose.StatusContext(ctx, dbConn, migrationsDir)
+}
+
+func migrateUp(ctx context.Context, _ *cli.Context, dbConn *sql.DB) error {
+	return goose.UpContext(ctx, dbConn, migrationsDir)
+}
+
+func migrateUpTo(ctx context.Context, cliCtx *cli.Context, dbConn *sql.DB) error {
+	version, err := strconv.ParseInt(cliCtx.Args().First(), 10, 64)
+	if nil != err {
+		return fmt.Errorf("target version must be given as the only argument: %v", err)
+	}
+	return goose.UpToContext(ctx, dbConn, migrationsDir, version)
+}
+
+func migrateDown(ctx context.Context, _ *cli.Context, dbConn *sql.DB) error {
+	return goose.DownContext(ctx, dbConn, migrationsDir)
+}
+
+func migrateRedo(ctx context.Context, _ *cli.Context, dbConn *sql.DB) error {
+	return goose.RedoContext(ctx, dbConn, migrationsDir)
+}
+
+func migrateVersion(ctx context.Context, _ *cli.Context, dbConn *sql.DB) error {
+	return goose.VersionContext(ctx, dbConn, migrationsDir)
+}
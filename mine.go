@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+)
+
+const (
+	mineCallbackPrefix = "mine:"
+	// minePageSize is the number of submissions listed on each page of the mine command.
+	minePageSize = 10
+)
+
+// handleMineCommand lists the most recent submissions of the user, with the keyboard of the older ones.
+func (h *Handler) handleMineCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscard(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
+	chatID := update.Message.Chat.ID
+
+	text, keyboard, err := h.submissionsPage(ctx, log, lang, update.Message.From.ID, 0)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to list submissions")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	msg := bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: markup.MarkdownV2.ParseMode(),
+	}
+	if nil != keyboard {
+		msg.ReplyMarkup = keyboard
+	}
+	if _, err := h.sender.SendMessage(ctx, b, &msg); nil != err {
+		log.Error().Err(err).Dict("reply_message", h.replyDict(chatID)).Msg("failed to send submissions reply message")
+	}
+}
+
+// handleMineCallback replaces the listed submissions by the page picked from the keyboard of the mine command.
+func (h *Handler) handleMineCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscardCallback(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	query := update.CallbackQuery
+	lang := h.userLanguage(ctx, log, &query.Sender)
+
+	page, err := strconv.Atoi(strings.TrimPrefix(query.Data, mineCallbackPrefix))
+	if nil != err || page < 0 {
+		h.answerCallback(ctx, b, log, query, "")
+		return
+	}
+	text, keyboard, err := h.submissionsPage(ctx, log, lang, query.Sender.ID, page)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to list submissions")
+		h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgInternalError, nil))
+		return
+	}
+	h.answerCallback(ctx, b, log, query, "")
+
+	msg := query.Message
+	err = h.sender.Do(ctx, msg.Chat.ID, func(ctx context.Context) error {
+		params := bot.EditMessageTextParams{
+			ChatID:    msg.Chat.ID,
+			MessageID: msg.ID,
+			Text:      text,
+			ParseMode: markup.MarkdownV2.ParseMode(),
+		}
+		if nil != keyboard {
+			params.ReplyMarkup = keyboard
+		}
+		_, err := b.EditMessageText(ctx, &params)
+		return err
+	})
+	if nil != err {
+		log.Error().Err(err).Int("page", page).Msg("failed to edit submissions message")
+	}
+}
+
+// submissionsPage returns the text of the page of the submissions of the user, with their status and tags, and the keyboard of the
+// adjacent pages, which is nil if there's only one. Pages past the last one, e.g. as older submissions were unlinked from
+// the user since the keyboard was sent, are replaced by the last page.
+func (h *Handler) submissionsPage(ctx context.Context, log zerolog.Logger, lang i18n.Lang, userID int64, page int) (string, *models.InlineKeyboardMarkup, error) {
+	submitter := h.pseudonymizer.ID(userID)
+	hosts, total, err := h.store.ListSubmissions(ctx, submitter, page*minePageSize, minePageSize)
+	if nil != err {
+		return "", nil, err
+	}
+	if total == 0 {
+		return h.text(lang, i18n.MsgNoSubmissions, nil), nil, nil
+	}
+	if len(hosts) == 0 {
+		page = (total - 1) / minePageSize
+		if hosts, total, err = h.store.ListSubmissions(ctx, submitter, page*minePageSize, minePageSize); nil != err {
+			return "", nil, err
+		}
+		if total == 0 {
+			return h.text(lang, i18n.MsgNoSubmissions, nil), nil, nil
+		}
+	}
+
+	submissions := make([]i18n.Params, 0, len(hosts))
+	for _, host := range hosts {
+		var publishedAt string
+		if nil != host.PublishedTs {
+			publishedAt = time.Unix(*host.PublishedTs, 0).UTC().Format("2006-01-02")
+		}
+		submissions = append(submissions, i18n.Params{
+			"Domain":      host.Host,
+			"Since":       time.Unix(host.CreatedTs, 0).UTC().Format("2006-01-02"),
+			"PublishedAt": publishedAt,
+			// Unpublished hosts are only awaiting publication if the list is published periodically.
+			"Pending": h.publishEnabled,
+			"Tags":    strings.Join(host.Tags, ", "),
+		})
+	}
+	log.Debug().Int("page", page).Int("total", total).Msg("listed submissions")
+	text := h.text(lang, i18n.MsgSubmissions, i18n.Params{
+		"From":        page*minePageSize + 1,
+		"To":          page*minePageSize + len(hosts),
+		"Total":       total,
+		"Submissions": submissions,
+	})
+
+	var row []models.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         h.plainText(lang, i18n.MsgPreviousPage, nil),
+			CallbackData: mineCallbackPrefix + strconv.Itoa(page-1),
+		})
+	}
+	if (page+1)*minePageSize < total {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         h.plainText(lang, i18n.MsgNextPage, nil),
+			CallbackData: mineCallbackPrefix + strconv.Itoa(page+1),
+		})
+	}
+	if len(row) == 0 {
+		return text, nil, nil
+	}
+	return text, &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}, nil
+}
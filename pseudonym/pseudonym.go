@@ -0,0 +1,49 @@
+package pseudonym
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const MinKeySize = 32
+
+var ErrKeyTooShort = fmt.Errorf("pseudonymization key must be at least %d bytes", MinKeySize)
+
+// ID is a keyed pseudonym of a Telegram user or chat identifier.
+// It's the only kind of identifier stored in the database.
+type ID int64
+
+type Pseudonymizer struct {
+	key []byte
+}
+
+func New(key []byte) (*Pseudonymizer, error) {
+	if len(key) < MinKeySize {
+		return nil, ErrKeyTooShort
+	}
+	return &Pseudonymizer{key: key}, nil
+}
+
+// NewFromHex is like New, but accepts a hex encoded key, e.g., generated using: openssl rand -hex 32
+func NewFromHex(key string) (*Pseudonymizer, error) {
+	decoded, err := hex.DecodeString(key)
+	if nil != err {
+		return nil, errors.New("pseudonymization key must be hex encoded")
+	}
+	return New(decoded)
+}
+
+// ID returns the HMAC-SHA256 of the identifier truncated to a positive 63 bits integer,
+// which is irreversible without the key, and stable for as long as the key is unchanged.
+func (p *Pseudonymizer) ID(id int64) ID {
+	mac := hmac.New(sha256.New, p.key)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(id))
+	mac.Write(buf[:])
+	sum := mac.Sum(nil)
+	return ID(binary.BigEndian.Uint64(sum[:8]) >> 1)
+}
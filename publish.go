@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/z4x7k/iran-domains-tg-bot/export"
+	"github.com/z4x7k/iran-domains-tg-bot/publish"
+	"github.com/z4x7k/iran-domains-tg-bot/sender"
+)
+
+func publishConfigFromEnv(cliCtx *cli.Context) (publish.Config, error) {
+	chatID, ok := os.LookupEnv(EnvKeyPublishChatID)
+	if !ok {
+		return publish.Config{}, fmt.Errorf("required environment variable '%s' is not set", EnvKeyPublishChatID)
+	}
+	formatsStr := DefaultPublishFormats
+	if val, ok := os.LookupEnv(EnvKeyPublishFormats); ok && val != "" {
+		formatsStr = val
+	}
+	formats, err := export.ParseFormats(formatsStr)
+	if nil != err {
+		return publish.Config{}, fmt.Errorf("invalid '%s': %v", EnvKeyPublishFormats, err)
+	}
+	var tags []string
+	if val, ok := os.LookupEnv(EnvKeyPublishTags); ok && val != "" {
+		if tags, err = export.ParseTags(val); nil != err {
+			return publish.Config{}, fmt.Errorf("invalid '%s': %v", EnvKeyPublishTags, err)
+		}
+	}
+	rulesStr := DefaultPublishRules
+	if val, ok := os.LookupEnv(EnvKeyPublishRules); ok && val != "" {
+		rulesStr = val
+	}
+	rules, err := export.ParseRules(rulesStr)
+	if nil != err {
+		return publish.Config{}, fmt.Errorf("invalid '%s': %v", EnvKeyPublishRules, err)
+	}
+	base := filepath.Base(dbFilename(cliCtx))
+
+	return publish.Config{
+		ChatID:     chatID,
+		Formats:    formats,
+		FilePrefix: strings.TrimSuffix(base, filepath.Ext(base)) + "-",
+		Tags:       tags,
+		Rules:      rules,
+	}, nil
+}
+
+// publishIntervalFromEnv returns the publication interval, and whether scheduled publication is enabled.
+func publishIntervalFromEnv() (time.Duration, bool, error) {
+	val, ok := os.LookupEnv(EnvKeyPublishInterval)
+	if !ok || val == "" {
+		return 0, false, nil
+	}
+	interval, err := time.ParseDuration(val)
+	if nil != err || interval <= 0 {
+		return 0, false, fmt.Errorf("'%s' must be a positive duration", EnvKeyPublishInterval)
+	}
+
+	return interval, true, nil
+}
+
+func logPublishReport(log zerolog.Logger, report publish.Report) {
+	if report.Skipped {
+		log.Debug().Int("domains", report.Domains).Msg("skipped publication as no domains, nor hosts, were added since the last one")
+		return
+	}
+	log.
+		Info().
+		Int("domains", report.Domains).
+		Int("new_domains", report.NewDomains).
+		Int("new_hosts", report.NewHosts).
+		Strs("files", report.Files).
+		Msg("published domains list")
+}
+
+func publishNow(log zerolog.Logger) func(*cli.Context) error {
+	return func(cliCtx *cli.Context) error {
+		ctx, cancel := signal.NotifyContext(cliCtx.Context, os.Interrupt)
+		defer cancel()
+
+		if err := loadEnv(log, cliCtx); nil != err {
+			return err
+		}
+		cfg, err := publishConfigFromEnv(cliCtx)
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+
+		dbConn, closeDB, err := openDatabase(ctx, log, cliCtx)
+		if nil != err {
+			return err
+		}
+		defer closeDB()
+
+		b, err := newBot(nil)
+		if nil != err {
+			return err
+		}
+
+		report, err := publish.New(dbConn, sender.New(), cfg).Publish(ctx, b, cliCtx.Bool(CLIPublishCommandForceFlag))
+		if nil != err {
+			return err
+		}
+		logPublishReport(log, report)
+
+		return nil
+	}
+}
@@ -0,0 +1,227 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/export"
+	"github.com/z4x7k/iran-domains-tg-bot/sender"
+)
+
+const dateFormat = "20060102"
+
+type Config struct {
+	ChatID  string
+	Formats []export.Format
+	// FilePrefix is prepended to the names of the posted documents.
+	FilePrefix string
+	// Tags are posted as separate lists of the domains with each tag, in addition to the full list.
+	Tags []string
+	// Rules selects whether the text lists hold apex domains, or the submitted hosts.
+	Rules export.Rules
+}
+
+type Report struct {
+	PublishedAt time.Time
+	// Skipped is set when no domains, nor hosts, were added since the last publication, hence nothing was posted.
+	Skipped    bool
+	Domains    int
+	NewDomains int
+	NewHosts   int
+	Files      []string
+}
+
+type document struct {
+	filename string
+	data     []byte
+}
+
+// Publisher posts the exported domains list, and a changelog of the domains added since the last publication,
+// as documents to the publish chat. Every publication is recorded in the publications table.
+type Publisher struct {
+	db     *sql.DB
+	sender *sender.Scheduler
+	cfg    Config
+}
+
+func New(db *sql.DB, s *sender.Scheduler, cfg Config) *Publisher {
+	return &Publisher{db: db, sender: s, cfg: cfg}
+}
+
+// Publish posts the current list. Unless force is set, nothing is posted if no domains, nor hosts, were added since the last publication.
+// If a document fails to be sent after others were posted, the publication is still recorded, so that the next one doesn't
+// post them again, and the error is returned along with the report of the posted documents.
+func (p *Publisher) Publish(ctx context.Context, b *bot.Bot, force bool) (Report, error) {
+	now := time.Now().UTC()
+	// Domains are listed up to the start of the current second, which is recorded as the publication time,
+	// so that domains created later within the same second are part of the next changelog.
+	cutoff := now.Truncate(time.Second)
+	report := Report{PublishedAt: cutoff}
+
+	last, err := p.lastPublication(ctx)
+	if nil != err {
+		return Report{}, err
+	}
+
+	domains, err := db.ListDomains(ctx, p.db, cutoff)
+	if nil != err {
+		return Report{}, err
+	}
+	hosts, err := db.ListHosts(ctx, p.db, cutoff)
+	if nil != err {
+		return Report{}, err
+	}
+	tags, err := db.ListDomainTags(ctx, p.db)
+	if nil != err {
+		return Report{}, err
+	}
+	var (
+		newDomains []model.Domains
+		newHosts   []model.Hosts
+	)
+	if nil != last {
+		for _, d := range domains {
+			if d.CreatedTs >= last.PublishedTs {
+				newDomains = append(newDomains, d)
+			}
+		}
+		for _, h := range hosts {
+			if h.CreatedTs >= last.PublishedTs {
+				newHosts = append(newHosts, h)
+			}
+		}
+	}
+	report.Domains, report.NewDomains, report.NewHosts = len(domains), len(newDomains), len(newHosts)
+	if nil != last && len(newDomains) == 0 && len(newHosts) == 0 && !force {
+		report.Skipped = true
+		return report, nil
+	}
+
+	docs, err := p.render(export.NewList(domains, hosts, tags, cutoff), export.NewList(newDomains, newHosts, tags, cutoff), last)
+	if nil != err {
+		return Report{}, err
+	}
+	caption := fmt.Sprintf("%d domains, and %d hosts, as of %s", len(domains), len(hosts), cutoff.Format(time.RFC3339))
+	if nil != last {
+		caption += fmt.Sprintf(", %d domains, and %d hosts, added since %s", len(newDomains), len(newHosts), time.Unix(last.PublishedTs, 0).UTC().Format(time.RFC3339))
+	}
+	var sendErr error
+	for i, doc := range docs {
+		params := bot.SendDocumentParams{ChatID: p.cfg.ChatID, DisableNotification: i > 0}
+		if i == 0 {
+			params.Caption = caption
+		}
+		err := p.sender.Do(ctx, p.cfg.ChatID, func(ctx context.Context) error {
+			// The reader is consumed by every attempt, so it's recreated on retries.
+			params.Document = &models.InputFileUpload{Filename: doc.filename, Data: bytes.NewReader(doc.data)}
+			_, err := b.SendDocument(ctx, &params)
+			return err
+		})
+		if nil != err {
+			sendErr = fmt.Errorf("publish: failed to send document '%s': %v", doc.filename, err)
+			break
+		}
+		report.Files = append(report.Files, doc.filename)
+	}
+	if nil != sendErr && len(report.Files) == 0 {
+		return Report{}, sendErr
+	}
+
+	stmt := table.Publications.
+		INSERT(table.Publications.MutableColumns).
+		MODEL(model.Publications{
+			PublishedTs:     cutoff.Unix(),
+			DomainsCount:    int64(len(domains)),
+			NewDomainsCount: int64(len(newDomains)),
+		})
+	err = db.Retry(ctx, func() error {
+		_, err := stmt.ExecContext(ctx, p.db)
+		return err
+	})
+	if nil != err {
+		return Report{}, db.WrapErr(err, "failed to record publication")
+	}
+
+	return report, sendErr
+}
+
+// Run publishes every interval until ctx is done.
+func (p *Publisher) Run(ctx context.Context, b *bot.Bot, interval time.Duration, onReport func(Report), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := p.Publish(ctx, b, false)
+			if nil != err {
+				if ctx.Err() != nil {
+					return
+				}
+				onError(err)
+				continue
+			}
+			onReport(report)
+		}
+	}
+}
+
+func (p *Publisher) lastPublication(ctx context.Context) (*model.Publications, error) {
+	var dest model.Publications
+	err := table.Publications.
+		SELECT(table.Publications.AllColumns).
+		ORDER_BY(table.Publications.ID.DESC()).
+		LIMIT(1).
+		QueryContext(ctx, p.db, &dest)
+	if nil != err {
+		if errors.Is(err, qrm.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, db.WrapErr(err, "failed to query last publication")
+	}
+
+	return &dest, nil
+}
+
+// render builds the list documents in the configured formats, then the ones of each configured tag, followed by the changelog,
+// which is omitted on the first publication, since every domain would be in it.
+func (p *Publisher) render(list export.List, added export.List, last *model.Publications) ([]document, error) {
+	cutoff := list.GeneratedAt
+	date := cutoff.Format(dateFormat)
+	var docs []document
+	for _, prefix := range append([]string{""}, p.cfg.Tags...) {
+		var filter []string
+		if prefix != "" {
+			filter = []string{prefix}
+			prefix += "-"
+		}
+		for _, f := range p.cfg.Formats {
+			var buf bytes.Buffer
+			if err := export.Write(&buf, f, p.cfg.Rules, list, filter); nil != err {
+				return nil, fmt.Errorf("publish: failed to export domains as %s: %v", f, err)
+			}
+			docs = append(docs, document{filename: p.cfg.FilePrefix + prefix + date + "." + string(f), data: buf.Bytes()})
+		}
+	}
+	if nil != last {
+		var buf bytes.Buffer
+		if err := export.WriteChangelog(&buf, added, nil, time.Unix(last.PublishedTs, 0), cutoff); nil != err {
+			return nil, fmt.Errorf("publish: failed to write changelog: %v", err)
+		}
+		docs = append(docs, document{filename: p.cfg.FilePrefix + "changelog-" + date + ".txt", data: buf.Bytes()})
+	}
+
+	return docs, nil
+}
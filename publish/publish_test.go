@@ -0,0 +1,149 @@
+package publish
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-telegram/bot"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/dbtest"
+	"github.com/z4x7k/iran-domains-tg-bot/export"
+	"github.com/z4x7k/iran-domains-tg-bot/sender"
+)
+
+// newBot returns a bot whose documents are sent to a fake server, which fails the document numbered failAt, counting from 1,
+// and the number of documents it received.
+func newBot(t *testing.T, failAt int32) (*bot.Bot, *atomic.Int32) {
+	t.Helper()
+	var documents atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/sendDocument") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if documents.Add(1) == failAt {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"ok":false,"error_code":400,"description":"Bad Request: file is too big"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`)
+	}))
+	t.Cleanup(srv.Close)
+	b, err := bot.New("token", bot.WithSkipGetMe(), bot.WithServerURL(srv.URL))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	return b, &documents
+}
+
+// newDB returns a database with a host submitted a minute ago, so that it's listed by publications of the current second.
+func newDB(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	if _, err := db.InsertHost(ctx, conn, "www.git.ir", "git.ir", 1); nil != err {
+		t.Fatal(err)
+	}
+	for _, q := range []string{"UPDATE domains SET created_ts = created_ts - 60", "UPDATE hosts SET created_ts = created_ts - 60"} {
+		if _, err := conn.ExecContext(ctx, q); nil != err {
+			t.Fatal(err)
+		}
+	}
+
+	return conn
+}
+
+func countPublications(t *testing.T, conn *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM publications").Scan(&n); nil != err {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	conn := newDB(t)
+	b, documents := newBot(t, 0)
+	p := New(conn, sender.New(), Config{ChatID: "1", Formats: []export.Format{export.FormatText}, FilePrefix: "ir-", Rules: export.RulesSuffix})
+
+	report, err := p.Publish(ctx, b, false)
+	if nil != err {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if want := []string{"ir-" + report.PublishedAt.Format(dateFormat) + ".txt"}; report.Skipped || report.Domains != 1 || !reflect.DeepEqual(report.Files, want) {
+		t.Errorf("Publish() = %+v, want %v posted, with a domain", report, want)
+	}
+	if n := countPublications(t, conn); n != 1 {
+		t.Errorf("publications = %d, want 1", n)
+	}
+
+	// Nothing was added since, so nothing is posted, unless forced, which also posts the changelog.
+	report, err = p.Publish(ctx, b, false)
+	if nil != err || !report.Skipped {
+		t.Fatalf("Publish() = %+v, %v, want it skipped", report, err)
+	}
+	report, err = p.Publish(ctx, b, true)
+	if nil != err || report.Skipped || len(report.Files) != 2 {
+		t.Fatalf("forced Publish() = %+v, %v, want the list and the changelog posted", report, err)
+	}
+	if n := documents.Load(); n != 3 {
+		t.Errorf("server received %d documents, want 3", n)
+	}
+}
+
+func TestPublishFailure(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config{ChatID: "1", Formats: []export.Format{export.FormatText, export.FormatJSON}, Rules: export.RulesSuffix}
+
+	t.Run("first document", func(t *testing.T) {
+		conn := newDB(t)
+		b, documents := newBot(t, 1)
+		p := New(conn, sender.New(), cfg)
+		if _, err := p.Publish(ctx, b, false); nil == err {
+			t.Fatal("Publish succeeded, want error")
+		}
+		if n := countPublications(t, conn); n != 0 {
+			t.Errorf("publications = %d, want none, so that the next one posts the list", n)
+		}
+		if _, err := p.Publish(ctx, b, false); nil != err {
+			t.Fatalf("Publish after a failure failed: %v", err)
+		}
+		if n := documents.Load(); n != 3 {
+			t.Errorf("server received %d documents, want the failed one, and then both", n)
+		}
+	})
+
+	t.Run("later document", func(t *testing.T) {
+		conn := newDB(t)
+		b, documents := newBot(t, 2)
+		p := New(conn, sender.New(), cfg)
+		report, err := p.Publish(ctx, b, false)
+		if nil == err {
+			t.Fatal("Publish succeeded, want error")
+		}
+		if len(report.Files) != 1 || !strings.HasSuffix(report.Files[0], ".txt") {
+			t.Errorf("Publish() files = %v, want the posted txt list", report.Files)
+		}
+		if n := countPublications(t, conn); n != 1 {
+			t.Errorf("publications = %d, want the partial one recorded", n)
+		}
+		if report, err := p.Publish(ctx, b, false); nil != err || !report.Skipped {
+			t.Errorf("Publish after a partial one = %+v, %v, want it skipped", report, err)
+		}
+		if n := documents.Load(); n != 2 {
+			t.Errorf("server received %d documents, want 2", n)
+		}
+	})
+}
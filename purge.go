@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/z4x7k/iran-domains-tg-bot/retention"
+)
+
+func retentionPolicyFromEnv() (retention.Policy, error) {
+	policy := retention.Policy{SubmitterMaxAge: DefaultRetentionSubmitterMaxAge}
+	if val, ok := os.LookupEnv(EnvKeyRetentionSubmitterMaxAge); ok && val != "" {
+		parsed, err := parseDuration(val)
+		if nil != err || parsed <= 0 {
+			return retention.Policy{}, fmt.Errorf("'%s' must be a positive duration, e.g., 30d", EnvKeyRetentionSubmitterMaxAge)
+		}
+		policy.SubmitterMaxAge = parsed
+	}
+
+	return policy, nil
+}
+
+func retentionIntervalFromEnv() (time.Duration, error) {
+	interval := DefaultRetentionInterval
+	if val, ok := os.LookupEnv(EnvKeyRetentionInterval); ok && val != "" {
+		parsed, err := time.ParseDuration(val)
+		if nil != err || parsed <= 0 {
+			return 0, fmt.Errorf("'%s' must be a positive duration", EnvKeyRetentionInterval)
+		}
+		interval = parsed
+	}
+
+	return interval, nil
+}
+
+func logPurgeReport(log zerolog.Logger, report retention.Report) {
+	log.
+		Info().
+		Bool("dry_run", report.DryRun).
+		Int64("rate_limit_rows_deleted", report.RateLimitRowsDeleted).
+		Int64("domains_unlinked", report.DomainsUnlinked).
+		Int64("hosts_unlinked", report.HostsUnlinked).
+		Dur("took", report.FinishedAt.Sub(report.StartedAt)).
+		Msg("purged stale personal data")
+}
+
+func purge(log zerolog.Logger) func(*cli.Context) error {
+	return func(cliCtx *cli.Context) error {
+		ctx, cancel := signal.NotifyContext(cliCtx.Context, os.Interrupt)
+		defer cancel()
+
+		if err := loadEnv(log, cliCtx); nil != err {
+			return err
+		}
+		policy, err := retentionPolicyFromEnv()
+		if nil != err {
+			return fmt.Errorf("env: %v", err)
+		}
+
+		dbConn, closeDB, err := openDatabase(ctx, log, cliCtx)
+		if nil != err {
+			return err
+		}
+		defer closeDB()
+
+		report, err := retention.Purge(ctx, dbConn, policy, cliCtx.Bool(CLIPurgeCommandDryRunFlag))
+		if nil != err {
+			return fmt.Errorf("retention: %v", err)
+		}
+		logPurgeReport(log, report)
+
+		return nil
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "", want: DefaultRetentionSubmitterMaxAge},
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "720h", want: 720 * time.Hour},
+		{in: "0d", wantErr: true},
+		{in: "month", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Setenv(EnvKeyRetentionSubmitterMaxAge, tt.in)
+		policy, err := retentionPolicyFromEnv()
+		if tt.wantErr {
+			if nil == err {
+				t.Errorf("retentionPolicyFromEnv() with %q = %v, want error", tt.in, policy)
+			}
+			continue
+		}
+		if nil != err || policy.SubmitterMaxAge != tt.want {
+			t.Errorf("retentionPolicyFromEnv() with %q = %v, %v, want %v", tt.in, policy.SubmitterMaxAge, err, tt.want)
+		}
+	}
+}
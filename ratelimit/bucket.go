@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket is an in-memory token bucket shared by all users.
+type TokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+// NewTokenBucket returns a full bucket refilling rate tokens per second up to burst tokens.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:     rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastFill: time.Now(),
+	}
+}
+
+// Allow takes a token if one is available without waiting.
+func (t *TokenBucket) Allow() bool {
+	return t.reserve(false) == 0
+}
+
+// Wait takes a token, blocking until one is available or ctx is done.
+func (t *TokenBucket) Wait(ctx context.Context) error {
+	delay := t.reserve(true)
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve returns how long the caller must wait for its token. If wait is false
+// and no token is available, nothing is taken and a non-zero delay is returned.
+func (t *TokenBucket) reserve(wait bool) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.tokens += now.Sub(t.lastFill).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.lastFill = now
+
+	if t.tokens >= 1 {
+		t.tokens--
+		return 0
+	}
+	delay := time.Duration((1 - t.tokens) / t.rate * float64(time.Second))
+	if wait {
+		// Tokens may go negative, so that waiters queue up behind each other.
+		t.tokens--
+	}
+	return delay
+}
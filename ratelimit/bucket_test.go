@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Run("allows bursts, and refills over time", func(t *testing.T) {
+		b := NewTokenBucket(50, 2)
+		for i := 0; i < 2; i++ {
+			if !b.Allow() {
+				t.Fatalf("Allow() #%d of a full bucket = false", i)
+			}
+		}
+		if b.Allow() {
+			t.Fatal("Allow() of an empty bucket = true")
+		}
+		time.Sleep(40 * time.Millisecond)
+		if !b.Allow() {
+			t.Fatal("Allow() of a refilled bucket = false")
+		}
+	})
+
+	t.Run("queues waiters", func(t *testing.T) {
+		b := NewTokenBucket(50, 1)
+		startedAt := time.Now()
+		for i := 0; i < 3; i++ {
+			if err := b.Wait(context.Background()); nil != err {
+				t.Fatalf("Wait() failed: %v", err)
+			}
+		}
+		// The first token is in the bucket, and the next two refill every 20ms.
+		if took := time.Since(startedAt); took < 35*time.Millisecond {
+			t.Errorf("Wait() of 3 tokens took %v, want at least 40ms", took)
+		}
+	})
+
+	t.Run("stops waiting once ctx is done", func(t *testing.T) {
+		b := NewTokenBucket(0.1, 1)
+		b.Allow()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := b.Wait(ctx); nil == err {
+			t.Fatal("Wait() of an empty bucket succeeded before ctx was done")
+		}
+	})
+}
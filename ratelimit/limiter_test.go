@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// testLimiter runs the tests every backend must pass. newLimiter must return a limiter of empty storage.
+func testLimiter(t *testing.T, newLimiter func(t *testing.T, policy Policy) Limiter) {
+	ctx := context.Background()
+	user, other := pseudonym.ID(1), pseudonym.ID(2)
+
+	t.Run("budget", func(t *testing.T) {
+		l := newLimiter(t, Policy{
+			OutcomeSucceeded: {{Limit: 3, Window: time.Hour}},
+			OutcomeFailed:    {{Limit: 2, Window: time.Hour}},
+		})
+		take := func(id pseudonym.ID, wantAllowed bool, wantRemaining int, outcomes ...Outcome) Result {
+			t.Helper()
+			res, err := l.Take(ctx, id, outcomes...)
+			if nil != err {
+				t.Fatalf("Take() failed: %v", err)
+			}
+			if res.Allowed != wantAllowed || res.Remaining != wantRemaining {
+				t.Fatalf("Take(%v) = %+v, want allowed %v with %d remaining", outcomes, res, wantAllowed, wantRemaining)
+			}
+			return res
+		}
+
+		if err := l.Refund(ctx, user, OutcomeFailed); nil != err {
+			t.Fatalf("Refund() of an unused budget failed: %v", err)
+		}
+		take(user, true, 2, OutcomeSucceeded, OutcomeFailed)
+		take(user, true, 1, OutcomeSucceeded, OutcomeFailed)
+		res := take(user, false, 0, OutcomeSucceeded, OutcomeFailed)
+		if wait := time.Until(res.RetryAt); wait <= 0 || wait > 30*time.Minute {
+			t.Errorf("Take() retry at %v, want within the emission interval of the failed window", res.RetryAt)
+		}
+		take(other, true, 3, OutcomeSucceeded)
+
+		if err := l.Refund(ctx, user, OutcomeFailed); nil != err {
+			t.Fatalf("Refund() failed: %v", err)
+		}
+		// The denied attempt took nothing, so 2 of 3 succeeded, and 1 of 2 failed, attempts are used.
+		take(user, true, 1, OutcomeSucceeded)
+		take(user, false, 0, OutcomeSucceeded)
+		// The budgets of the outcomes are independent, so the exhausted succeeded budget doesn't deny failed attempts.
+		take(user, true, 1, OutcomeFailed)
+		take(user, false, 0, OutcomeFailed)
+		if err := l.Refund(ctx, user, OutcomeSucceeded); nil != err {
+			t.Fatalf("Refund() failed: %v", err)
+		}
+		take(user, true, 1, OutcomeSucceeded)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		const limit = 5
+		l := newLimiter(t, Policy{OutcomeSucceeded: {{Limit: limit, Window: time.Hour}}})
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 4*limit; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := l.Take(ctx, user, OutcomeSucceeded)
+				if nil != err {
+					t.Errorf("Take() failed: %v", err)
+					return
+				}
+				if res.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := allowed.Load(); n != limit {
+			t.Errorf("%d concurrent attempts were allowed, want %d", n, limit)
+		}
+	})
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	testLimiter(t, func(t *testing.T, policy Policy) Limiter {
+		return NewMemory(nil, policy)
+	})
+}
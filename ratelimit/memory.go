@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// MemoryLimiter keeps theoretical arrival times in memory, and periodically
+// snapshots them to the users_rate_limit table, so that restarts don't reset budgets.
+// Budgets are only shared within the process, and a snapshot replaces the whole table, overwriting the budgets
+// written by any other process, so the database must not be shared by other instances using it.
+type MemoryLimiter struct {
+	db     *sql.DB
+	policy Policy
+
+	mu    sync.Mutex
+	users map[pseudonym.ID]arrivals
+}
+
+func NewMemory(db *sql.DB, policy Policy) *MemoryLimiter {
+	return &MemoryLimiter{
+		db:     db,
+		policy: policy,
+		users:  map[pseudonym.ID]arrivals{},
+	}
+}
+
+func (m *MemoryLimiter) Take(_ context.Context, userID pseudonym.ID, outcomes ...Outcome) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res, taken := take(m.policy, m.users[userID], time.Now().UTC().UnixMilli(), outcomes)
+	m.store(userID, taken)
+
+	return res, nil
+}
+
+func (m *MemoryLimiter) Refund(_ context.Context, userID pseudonym.ID, outcome Outcome) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store(userID, refund(m.policy, m.users[userID], time.Now().UTC().UnixMilli(), outcome))
+
+	return nil
+}
+
+// store sets the arrival times of the user. It must be called with mu held.
+func (m *MemoryLimiter) store(userID pseudonym.ID, updated arrivals) {
+	if len(updated) == 0 {
+		return
+	}
+	tats, ok := m.users[userID]
+	if !ok {
+		tats = arrivals{}
+		m.users[userID] = tats
+	}
+	for outcome, windows := range updated {
+		for windowSeconds, tat := range windows {
+			tats.set(outcome, windowSeconds, tat)
+		}
+	}
+}
+
+// Restore loads the last snapshot, skipping windows which are already replenished.
+func (m *MemoryLimiter) Restore(ctx context.Context) error {
+	var rows []model.UsersRateLimit
+	err := table.UsersRateLimit.
+		SELECT(table.UsersRateLimit.AllColumns).
+		WHERE(table.UsersRateLimit.TheoreticalArrivalMs.GT(sqlite.Int64(time.Now().UTC().UnixMilli()))).
+		QueryContext(ctx, m.db, &rows)
+	if nil != err {
+		return db.WrapErr(err, "failed to query user rate limit snapshot")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, row := range rows {
+		tats, ok := m.users[pseudonym.ID(row.TheUserID)]
+		if !ok {
+			tats = arrivals{}
+			m.users[pseudonym.ID(row.TheUserID)] = tats
+		}
+		tats.set(Outcome(row.Outcome), row.WindowSeconds, row.TheoreticalArrivalMs)
+	}
+
+	return nil
+}
+
+// Snapshot replaces the users_rate_limit table content with windows which are not yet replenished,
+// and evicts replenished ones from memory.
+func (m *MemoryLimiter) Snapshot(ctx context.Context) error {
+	now := time.Now().UTC().UnixMilli()
+	var rows []model.UsersRateLimit
+	m.mu.Lock()
+	for userID, tats := range m.users {
+		for outcome, windows := range tats {
+			for windowSeconds, tat := range windows {
+				if tat <= now {
+					delete(windows, windowSeconds)
+					continue
+				}
+				rows = append(rows, model.UsersRateLimit{
+					TheUserID:            int64(userID),
+					Outcome:              string(outcome),
+					WindowSeconds:        windowSeconds,
+					TheoreticalArrivalMs: tat,
+				})
+			}
+			if len(windows) == 0 {
+				delete(tats, outcome)
+			}
+		}
+		if len(tats) == 0 {
+			delete(m.users, userID)
+		}
+	}
+	m.mu.Unlock()
+
+	return db.Retry(ctx, func() error { return m.writeSnapshot(ctx, rows) })
+}
+
+func (m *MemoryLimiter) writeSnapshot(ctx context.Context, rows []model.UsersRateLimit) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if nil != err {
+		return db.WrapErr(err, "failed to begin rate limit snapshot transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := table.UsersRateLimit.DELETE().WHERE(sqlite.Bool(true)).ExecContext(ctx, tx); nil != err {
+		return db.WrapErr(err, "failed to delete previous rate limit snapshot")
+	}
+	// Insert in chunks to stay below the maximum number of host parameters of SQLite.
+	const chunkSize = 200
+	for i := 0; i < len(rows); i += chunkSize {
+		end := i + chunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if _, err := table.UsersRateLimit.INSERT(table.UsersRateLimit.AllColumns).MODELS(rows[i:end]).ExecContext(ctx, tx); nil != err {
+			return db.WrapErr(err, "failed to insert rate limit snapshot")
+		}
+	}
+	if err := tx.Commit(); nil != err {
+		return db.WrapErr(err, "failed to commit rate limit snapshot transaction")
+	}
+
+	return nil
+}
+
+// Run snapshots every interval until ctx is done, then takes a final snapshot.
+// Errors are passed to onError.
+func (m *MemoryLimiter) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.Snapshot(context.Background()); nil != err {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := m.Snapshot(ctx); nil != err {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Outcome string
+
+const (
+	OutcomeSucceeded Outcome = "succeeded"
+	OutcomeFailed    Outcome = "failed"
+	// OutcomeGroup counts every submission in a group chat, either succeeded or failed, keyed by the group rather than the submitter.
+	// Groups are never recorded under the other outcomes, and users never under this one, so a single policy holds both.
+	OutcomeGroup Outcome = "group"
+)
+
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Policy holds the rules applied to each submission outcome. Every rule
+// is a separate window, and a user is limited once any of them is exhausted.
+type Policy map[Outcome][]Rule
+
+// ParseRules parses a comma separated list of limit/window pairs, e.g., "10/1m,100/1h,300/1d".
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	windows := map[int64]bool{}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		parts := strings.SplitN(v, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("rate limit rule '%s' must be in limit/window format", v)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if nil != err || limit < 1 {
+			return nil, fmt.Errorf("rate limit rule '%s' must have a positive integer limit", v)
+		}
+		window, err := parseWindow(strings.TrimSpace(parts[1]))
+		if nil != err {
+			return nil, fmt.Errorf("rate limit rule '%s' has invalid window: %v", v, err)
+		}
+		// Windows are stored by their number of seconds, so fractions of a second would be lost.
+		if window < time.Second || window%time.Second != 0 {
+			return nil, fmt.Errorf("rate limit rule '%s' window must be a whole number of seconds", v)
+		}
+		// Emission intervals are whole milliseconds, which must not round down to zero.
+		if int64(limit) > window.Milliseconds() {
+			return nil, fmt.Errorf("rate limit rule '%s' must not allow more than one attempt per millisecond", v)
+		}
+		rule := Rule{Limit: limit, Window: window}
+		if windows[rule.windowSeconds()] {
+			return nil, fmt.Errorf("rate limit rule '%s' window is used by another rule", v)
+		}
+		windows[rule.windowSeconds()] = true
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("rate limit rules list '%s' is empty", s)
+	}
+
+	return rules, nil
+}
+
+// ParsePolicy parses a policy file, which has a line of rules, in ParseRules format, per outcome, e.g.:
+//
+//	succeeded = 10/1m,100/1h,300/1d
+//	failed = 20/1h
+//
+// Blank lines, and lines starting with #, are ignored. Outcomes which aren't listed are left out of the policy.
+func ParsePolicy(r io.Reader) (Policy, error) {
+	policy := Policy{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rulesStr, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d must be in outcome = rules format", n)
+		}
+		outcome := Outcome(strings.TrimSpace(name))
+		switch outcome {
+		case OutcomeSucceeded, OutcomeFailed, OutcomeGroup:
+		default:
+			return nil, fmt.Errorf("line %d has unknown outcome '%s'", n, outcome)
+		}
+		if _, ok := policy[outcome]; ok {
+			return nil, fmt.Errorf("line %d has outcome '%s' of a previous line", n, outcome)
+		}
+		rules, err := ParseRules(rulesStr)
+		if nil != err {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		policy[outcome] = rules
+	}
+	if err := scanner.Err(); nil != err {
+		return nil, fmt.Errorf("failed to read rate limit policy: %v", err)
+	}
+
+	return policy, nil
+}
+
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if nil != err {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// interval returns the emission interval of the rule in milliseconds.
+func (r Rule) interval() int64 {
+	return r.Window.Milliseconds() / int64(r.Limit)
+}
+
+func (r Rule) windowSeconds() int64 {
+	return int64(r.Window.Seconds())
+}
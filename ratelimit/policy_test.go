@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Rule
+		wantErr bool
+	}{
+		{name: "single", in: "10/1m", want: []Rule{{Limit: 10, Window: time.Minute}}},
+		{
+			name: "multiple with spaces and days",
+			in:   " 10/1m, 100/1h ,300/1d",
+			want: []Rule{{Limit: 10, Window: time.Minute}, {Limit: 100, Window: time.Hour}, {Limit: 300, Window: 24 * time.Hour}},
+		},
+		{name: "empty entries are skipped", in: "10/1m,,", want: []Rule{{Limit: 10, Window: time.Minute}}},
+		{name: "one attempt per millisecond", in: "1000/1s", want: []Rule{{Limit: 1000, Window: time.Second}}},
+		{name: "empty", in: "", wantErr: true},
+		{name: "missing window", in: "10", wantErr: true},
+		{name: "zero limit", in: "0/1m", wantErr: true},
+		{name: "negative limit", in: "-1/1m", wantErr: true},
+		{name: "invalid window", in: "10/forever", wantErr: true},
+		{name: "invalid days", in: "10/xd", wantErr: true},
+		{name: "window below a second", in: "1/500ms", wantErr: true},
+		{name: "fractional seconds window", in: "1/1500ms", wantErr: true},
+		{name: "more than one attempt per millisecond", in: "2000/1s", wantErr: true},
+		{name: "duplicate window", in: "10/1m,20/60s", wantErr: true},
+		{name: "duplicate window in days", in: "10/24h,20/1d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRules(tt.in)
+			if tt.wantErr {
+				if nil == err {
+					t.Fatalf("ParseRules(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if nil != err {
+				t.Fatalf("ParseRules(%q) failed: %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRules(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Policy
+		wantErr bool
+	}{
+		{
+			name: "every outcome",
+			in:   "# Budgets\nsucceeded = 10/1m,300/1d\n\nfailed=20/1h\n  group = 60/1h\n",
+			want: Policy{
+				OutcomeSucceeded: {{Limit: 10, Window: time.Minute}, {Limit: 300, Window: 24 * time.Hour}},
+				OutcomeFailed:    {{Limit: 20, Window: time.Hour}},
+				OutcomeGroup:     {{Limit: 60, Window: time.Hour}},
+			},
+		},
+		{name: "missing outcomes are left out", in: "failed = 20/1h", want: Policy{OutcomeFailed: {{Limit: 20, Window: time.Hour}}}},
+		{name: "empty", in: "", want: Policy{}},
+		{name: "missing separator", in: "succeeded 10/1m", wantErr: true},
+		{name: "unknown outcome", in: "banned = 10/1m", wantErr: true},
+		{name: "duplicate outcome", in: "failed = 10/1m\nfailed = 20/1h", wantErr: true},
+		{name: "invalid rules", in: "failed = 10/1ms", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(strings.NewReader(tt.in))
+			if tt.wantErr {
+				if nil == err {
+					t.Fatalf("ParsePolicy(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if nil != err {
+				t.Fatalf("ParsePolicy(%q) failed: %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePolicy(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/postgres"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	pg "github.com/z4x7k/iran-domains-tg-bot/db/postgres"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// PostgresLimiter keeps theoretical arrival times in the users_rate_limit table of a PostgreSQL database.
+type PostgresLimiter struct {
+	db     *sql.DB
+	policy Policy
+}
+
+func NewPostgres(db *sql.DB, policy Policy) *PostgresLimiter {
+	return &PostgresLimiter{
+		db:     db,
+		policy: policy,
+	}
+}
+
+func (r *PostgresLimiter) Take(ctx context.Context, userID pseudonym.ID, outcomes ...Outcome) (Result, error) {
+	var res Result
+	err := r.update(ctx, userID, func(tats arrivals, now int64) arrivals {
+		var taken arrivals
+		res, taken = take(r.policy, tats, now, outcomes)
+		return taken
+	})
+	if nil != err {
+		return Result{}, err
+	}
+
+	return res, nil
+}
+
+func (r *PostgresLimiter) Refund(ctx context.Context, userID pseudonym.ID, outcome Outcome) error {
+	return r.update(ctx, userID, func(tats arrivals, now int64) arrivals {
+		return refund(r.policy, tats, now, outcome)
+	})
+}
+
+// update passes the arrival times of the user to fn, and stores the ones it returns, in a single transaction.
+func (r *PostgresLimiter) update(ctx context.Context, userID pseudonym.ID, fn func(tats arrivals, now int64) arrivals) error {
+	err := r.updateTx(ctx, userID, fn)
+	if nil != err {
+		if pg.IsBusy(err) {
+			return db.ErrBusy
+		}
+		return fmt.Errorf("db: failed to update user rate limit counters: %v", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresLimiter) updateTx(ctx context.Context, userID pseudonym.ID, fn func(tats arrivals, now int64) arrivals) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if nil != err {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Row locks don't cover the windows which have no rows yet, so transactions of the same user are serialized
+	// by a lock of the user instead, which is released on commit, or rollback.
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", int64(userID)); nil != err {
+		return err
+	}
+	var rows []model.UsersRateLimit
+	err = table.UsersRateLimit.
+		SELECT(table.UsersRateLimit.AllColumns).
+		WHERE(table.UsersRateLimit.TheUserID.EQ(postgres.Int64(int64(userID)))).
+		QueryContext(ctx, tx, &rows)
+	if nil != err {
+		return err
+	}
+	tats := arrivals{}
+	for _, row := range rows {
+		tats.set(Outcome(row.Outcome), row.WindowSeconds, row.TheoreticalArrivalMs)
+	}
+
+	var updated []model.UsersRateLimit
+	for outcome, windows := range fn(tats, time.Now().UTC().UnixMilli()) {
+		for windowSeconds, tat := range windows {
+			updated = append(updated, model.UsersRateLimit{
+				TheUserID:            int64(userID),
+				Outcome:              string(outcome),
+				WindowSeconds:        windowSeconds,
+				TheoreticalArrivalMs: tat,
+			})
+		}
+	}
+	if len(updated) > 0 {
+		_, err = table.UsersRateLimit.
+			INSERT(table.UsersRateLimit.AllColumns).
+			MODELS(updated).
+			ON_CONFLICT(table.UsersRateLimit.TheUserID, table.UsersRateLimit.Outcome, table.UsersRateLimit.WindowSeconds).
+			DO_UPDATE(postgres.SET(table.UsersRateLimit.TheoreticalArrivalMs.SET(table.UsersRateLimit.EXCLUDED.TheoreticalArrivalMs))).
+			ExecContext(ctx, tx)
+		if nil != err {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,13 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/dbtest"
+)
+
+func TestPostgresLimiter(t *testing.T) {
+	testLimiter(t, func(t *testing.T, policy Policy) Limiter {
+		return NewPostgres(dbtest.Postgres(t), policy)
+	})
+}
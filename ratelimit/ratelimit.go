@@ -2,95 +2,127 @@ package ratelimit
 
 import (
 	"context"
-	"database/sql"
-	"errors"
-	"fmt"
 	"time"
 
-	"github.com/go-jet/jet/v2/sqlite"
-	"github.com/mattn/go-sqlite3"
-
-	"github.com/z4x7k/iran-domains-tg-bot/db"
-	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
-	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
 )
 
-type RateLimiter struct {
-	db          *sql.DB
-	maxAttempts int
-	interval    time.Duration
+// Limiter enforces the rules of a policy per user, keeping the attempts of each user in its storage.
+// Implementations must be safe for concurrent use. SQLiteLimiter, PostgresLimiter, and RedisLimiter are also safe
+// for use by several processes sharing their storage, while MemoryLimiter is only safe within a single process.
+type Limiter interface {
+	// Take reports the state of every window of the outcomes before the attempt, and, only if the user is allowed,
+	// consumes one attempt from every window of each of them, as a single atomic operation, so that concurrent attempts
+	// can't exceed the budget. The budgets of other outcomes are neither checked, nor consumed.
+	Take(ctx context.Context, userID pseudonym.ID, outcomes ...Outcome) (Result, error)
+	// Refund gives back one attempt to every window of the outcome, e.g. once an attempt turns out not to count.
+	Refund(ctx context.Context, userID pseudonym.ID, outcome Outcome) error
+}
+
+type Result struct {
+	Allowed bool
+	// Remaining is the number of attempts left in the most restrictive window.
+	Remaining int
+	// RetryAt is the earliest time the next attempt is allowed. It's zero if the user is allowed.
+	RetryAt time.Time
+	// ResetAt is the time at which all windows are fully replenished.
+	ResetAt time.Time
 }
 
-func New(db *sql.DB, MaxAttempts int, Interval time.Duration) RateLimiter {
-	return RateLimiter{
-		db:          db,
-		maxAttempts: MaxAttempts,
-		interval:    Interval,
+// arrivals maps outcomes to theoretical arrival times in milliseconds keyed by window seconds.
+type arrivals map[Outcome]map[int64]int64
+
+func (a arrivals) set(outcome Outcome, windowSeconds int64, tat int64) {
+	if a[outcome] == nil {
+		a[outcome] = map[int64]int64{}
 	}
+	a[outcome][windowSeconds] = tat
 }
 
-func (r *RateLimiter) CanPass(ctx context.Context, userID int64) (bool, error) {
-	now := time.Now().UTC().Unix()
-	intervalMillis := int64(r.interval.Seconds())
-	query, args := table.UsersRateLimit.
-		INSERT(table.UsersRateLimit.AllColumns).
-		MODEL(model.UsersRateLimit{TheUserID: userID, LastAccessTs: now, TheCount: 0}).
-		ON_CONFLICT(table.UsersRateLimit.TheUserID).
-		DO_UPDATE(
-			sqlite.
-				SET(
-					table.UsersRateLimit.LastAccessTs.SET(
-						sqlite.
-							CAST(
-								sqlite.
-									CASE().
-									WHEN(
-										sqlite.OR(
-											sqlite.Int64(now-intervalMillis).GT(table.UsersRateLimit.LastAccessTs),
-											sqlite.AND(
-												sqlite.Int64(now-intervalMillis).LT(table.UsersRateLimit.LastAccessTs),
-												table.UsersRateLimit.TheCount.LT(sqlite.Int(int64(r.maxAttempts))),
-											),
-										),
-									).
-									THEN(sqlite.Int64(now)).
-									ELSE(table.UsersRateLimit.LastAccessTs),
-							).
-							AS_INTEGER(),
-					),
-					table.UsersRateLimit.TheCount.SET(
-						sqlite.
-							CAST(
-								sqlite.
-									CASE().
-									WHEN(
-										sqlite.Int64(now-intervalMillis).GT(table.UsersRateLimit.LastAccessTs),
-									).
-									THEN(sqlite.Int64(0)).
-									WHEN(
-										sqlite.AND(
-											sqlite.Int64(now-intervalMillis).LT(table.UsersRateLimit.LastAccessTs),
-											table.UsersRateLimit.TheCount.LT(sqlite.Int(int64(r.maxAttempts))),
-										),
-									).
-									THEN(table.UsersRateLimit.TheCount.ADD(sqlite.Int(1))).
-									ELSE(table.UsersRateLimit.TheCount),
-							).
-							AS_INTEGER(),
-					),
-				),
-		).
-		RETURNING(table.UsersRateLimit.TheCount).
-		Sql()
-	var theCount int
-	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&theCount); nil != err {
-		var sqlErr sqlite3.Error
-		if errors.As(err, &sqlErr) {
-			if sqlErr.Code == sqlite3.ErrBusy && sqlErr.Error() == "database is locked" {
-				return false, db.ErrBusy
+// evaluate implements the generic cell rate algorithm (GCRA) for every rule of the outcomes in the policy.
+// Each rule of limit attempts per window admits one attempt every window/limit, and allows
+// bursts of up to limit attempts. A single theoretical arrival time is stored per rule, which
+// makes it a true sliding window without keeping a log of every attempt.
+func evaluate(policy Policy, tats arrivals, now int64, outcomes []Outcome) Result {
+	res := Result{Allowed: true, Remaining: -1, ResetAt: time.UnixMilli(now).UTC()}
+	for _, outcome := range outcomes {
+		for _, rule := range policy[outcome] {
+			window := rule.Window.Milliseconds()
+			interval := rule.interval()
+			tat := tats[outcome][rule.windowSeconds()]
+			if tat < now {
+				tat = now
+			}
+
+			remaining := int((now + window - tat) / interval)
+			if remaining > rule.Limit {
+				remaining = rule.Limit
+			}
+			if res.Remaining < 0 || remaining < res.Remaining {
+				res.Remaining = remaining
+			}
+			if resetAt := time.UnixMilli(tat).UTC(); resetAt.After(res.ResetAt) {
+				res.ResetAt = resetAt
+			}
+			if remaining < 1 {
+				res.Allowed = false
+				if retryAt := time.UnixMilli(tat + interval - window).UTC(); retryAt.After(res.RetryAt) {
+					res.RetryAt = retryAt
+				}
 			}
 		}
-		return false, fmt.Errorf("db: failed to query user rate limit counter: %v", err)
 	}
-	return theCount < r.maxAttempts, nil
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+
+	return res
+}
+
+// take evaluates the rules of the outcomes, and, if the user is allowed, returns the arrival times of every window
+// of the outcomes after consuming one attempt from each.
+func take(policy Policy, tats arrivals, now int64, outcomes []Outcome) (Result, arrivals) {
+	res := evaluate(policy, tats, now, outcomes)
+	if !res.Allowed {
+		return res, nil
+	}
+	taken := arrivals{}
+	for _, outcome := range outcomes {
+		for _, rule := range policy[outcome] {
+			taken.set(outcome, rule.windowSeconds(), advance(rule, tats[outcome][rule.windowSeconds()], now))
+		}
+	}
+
+	return res, taken
+}
+
+// refund returns the arrival times of every window of the outcome after giving back one attempt to each.
+// Windows which are already replenished are left out.
+func refund(policy Policy, tats arrivals, now int64, outcome Outcome) arrivals {
+	refunded := arrivals{}
+	for _, rule := range policy[outcome] {
+		tat := tats[outcome][rule.windowSeconds()]
+		if tat <= now {
+			continue
+		}
+		if tat -= rule.interval(); tat < now {
+			tat = now
+		}
+		refunded.set(outcome, rule.windowSeconds(), tat)
+	}
+
+	return refunded
+}
+
+// advance returns the theoretical arrival time after consuming one attempt,
+// which is max(tat, now) + interval, capped to a full window ahead of now.
+func advance(rule Rule, tat int64, now int64) int64 {
+	if tat < now {
+		tat = now
+	}
+	tat += rule.interval()
+	if capped := now + rule.Window.Milliseconds(); tat > capped {
+		return capped
+	}
+	return tat
 }
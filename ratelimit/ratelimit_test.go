@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	const now = int64(1_700_000_000_000)
+	at := func(ms int64) time.Time { return time.UnixMilli(ms).UTC() }
+	policy := Policy{
+		OutcomeSucceeded: {{Limit: 2, Window: time.Minute}, {Limit: 10, Window: time.Hour}},
+		OutcomeFailed:    {{Limit: 1, Window: time.Minute}},
+	}
+	minute, hour := int64(60), int64(3600)
+
+	both := []Outcome{OutcomeSucceeded, OutcomeFailed}
+
+	tests := []struct {
+		name     string
+		tats     arrivals
+		outcomes []Outcome
+		want     Result
+	}{
+		{
+			name: "no attempts",
+			tats: nil,
+			want: Result{Allowed: true, Remaining: 1, ResetAt: at(now)},
+		},
+		{
+			name: "replenished windows count as unused",
+			tats: arrivals{OutcomeFailed: {minute: now - 1}},
+			want: Result{Allowed: true, Remaining: 1, ResetAt: at(now)},
+		},
+		{
+			name: "half of a window used",
+			tats: arrivals{OutcomeSucceeded: {minute: now + 30_000, hour: now + 360_000}},
+			want: Result{Allowed: true, Remaining: 1, ResetAt: at(now + 360_000)},
+		},
+		{
+			name: "window exhausted",
+			tats: arrivals{OutcomeSucceeded: {minute: now + 60_000, hour: now + 720_000}},
+			want: Result{Allowed: false, Remaining: 0, RetryAt: at(now + 30_000), ResetAt: at(now + 720_000)},
+		},
+		{
+			name: "exhausted window of a taken outcome",
+			tats: arrivals{OutcomeFailed: {minute: now + 60_000}},
+			want: Result{Allowed: false, Remaining: 0, RetryAt: at(now + 60_000), ResetAt: at(now + 60_000)},
+		},
+		{
+			name:     "exhausted window of an outcome which isn't taken",
+			tats:     arrivals{OutcomeFailed: {minute: now + 60_000}},
+			outcomes: []Outcome{OutcomeSucceeded},
+			want:     Result{Allowed: true, Remaining: 2, ResetAt: at(now)},
+		},
+		{
+			name: "retry waits for the latest of the exhausted windows",
+			tats: arrivals{OutcomeSucceeded: {minute: now + 60_000, hour: now + 3_600_000}, OutcomeFailed: {minute: now + 60_000}},
+			want: Result{Allowed: false, Remaining: 0, RetryAt: at(now + 360_000), ResetAt: at(now + 3_600_000)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcomes := tt.outcomes
+			if nil == outcomes {
+				outcomes = both
+			}
+			if got := evaluate(policy, tt.tats, now, outcomes); got != tt.want {
+				t.Errorf("evaluate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	const now = int64(1_700_000_000_000)
+	rule := Rule{Limit: 4, Window: time.Minute}
+
+	tests := []struct {
+		name string
+		tat  int64
+		want int64
+	}{
+		{name: "unused", tat: 0, want: now + 15_000},
+		{name: "replenished", tat: now - 1, want: now + 15_000},
+		{name: "partially used", tat: now + 15_000, want: now + 30_000},
+		{name: "capped to a window ahead", tat: now + 50_000, want: now + 60_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := advance(rule, tt.tat, now); got != tt.want {
+				t.Errorf("advance(%d) = %d, want %d", tt.tat, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// takeScript returns the theoretical arrival times of every window key, which are never earlier than now, and,
+// only if none of the windows is exhausted, advances the ones taken from, as a single atomic operation.
+// ARGV holds now, followed by the interval, the window, and whether it's taken from, of each key.
+// Keys expire once their window is replenished, as they're equivalent to missing keys afterwards.
+const takeScript = `
+local now = tonumber(ARGV[1])
+local tats = {}
+local allowed = true
+for i, key in ipairs(KEYS) do
+	local tat = tonumber(redis.call('GET', key) or now)
+	if tat < now then
+		tat = now
+	end
+	tats[i] = tat
+	if now + tonumber(ARGV[i * 3]) - tat < tonumber(ARGV[i * 3 - 1]) then
+		allowed = false
+	end
+end
+if allowed then
+	for i, key in ipairs(KEYS) do
+		if ARGV[i * 3 + 1] == '1' then
+			local tat = math.min(tats[i] + tonumber(ARGV[i * 3 - 1]), now + tonumber(ARGV[i * 3]))
+			redis.call('SET', key, tat, 'PX', tat - now)
+		end
+	end
+end
+return tats
+`
+
+// refundScript moves the theoretical arrival time of every window key back by its interval, which is in ARGV after now.
+const refundScript = `
+local now = tonumber(ARGV[1])
+for i, key in ipairs(KEYS) do
+	local tat = tonumber(redis.call('GET', key) or now) - tonumber(ARGV[i + 1])
+	if tat > now then
+		redis.call('SET', key, tat, 'PX', tat - now)
+	else
+		redis.call('DEL', key)
+	end
+end
+return 0
+`
+
+// RedisLimiter keeps theoretical arrival times in any server speaking the Redis protocol.
+type RedisLimiter struct {
+	client *respClient
+	prefix string
+	policy Policy
+}
+
+func NewRedis(rawURL string, policy Policy) (*RedisLimiter, error) {
+	client, err := newRESPClient(rawURL)
+	if nil != err {
+		return nil, err
+	}
+
+	return &RedisLimiter{
+		client: client,
+		prefix: "ratelimit",
+		policy: policy,
+	}, nil
+}
+
+func (r *RedisLimiter) key(userID pseudonym.ID, outcome Outcome, windowSeconds int64) string {
+	return fmt.Sprintf("%s:%d:%s:%d", r.prefix, userID, outcome, windowSeconds)
+}
+
+func (r *RedisLimiter) Take(ctx context.Context, userID pseudonym.ID, outcomes ...Outcome) (Result, error) {
+	type window struct {
+		outcome       Outcome
+		windowSeconds int64
+	}
+	now := time.Now().UTC().UnixMilli()
+	var windows []window
+	var keys []string
+	argv := []string{strconv.FormatInt(now, 10)}
+	for _, outcome := range outcomes {
+		for _, rule := range r.policy[outcome] {
+			windows = append(windows, window{outcome: outcome, windowSeconds: rule.windowSeconds()})
+			keys = append(keys, r.key(userID, outcome, rule.windowSeconds()))
+			argv = append(argv, strconv.FormatInt(rule.interval(), 10), strconv.FormatInt(rule.Window.Milliseconds(), 10), "1")
+		}
+	}
+	if len(windows) == 0 {
+		return Result{Allowed: true}, nil
+	}
+
+	args := append([]string{"EVAL", takeScript, strconv.Itoa(len(keys))}, keys...)
+	reply, err := r.client.Do(ctx, append(args, argv...)...)
+	if nil != err {
+		return Result{}, fmt.Errorf("redis: failed to update user rate limit counters: %v", err)
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != len(windows) {
+		return Result{}, fmt.Errorf("redis: unexpected reply to take script: %v", reply)
+	}
+	tats := arrivals{}
+	for i, v := range values {
+		tat, ok := v.(int64)
+		if !ok {
+			return Result{}, fmt.Errorf("redis: invalid theoretical arrival time '%v'", v)
+		}
+		tats.set(windows[i].outcome, windows[i].windowSeconds, tat)
+	}
+
+	// The script applies the same condition as evaluate to the same arrival times.
+	return evaluate(r.policy, tats, now, outcomes), nil
+}
+
+func (r *RedisLimiter) Refund(ctx context.Context, userID pseudonym.ID, outcome Outcome) error {
+	rules := r.policy[outcome]
+	if len(rules) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(rules))
+	argv := []string{strconv.FormatInt(time.Now().UTC().UnixMilli(), 10)}
+	for _, rule := range rules {
+		keys = append(keys, r.key(userID, outcome, rule.windowSeconds()))
+		argv = append(argv, strconv.FormatInt(rule.interval(), 10))
+	}
+	args := append([]string{"EVAL", refundScript, strconv.Itoa(len(keys))}, keys...)
+	if _, err := r.client.Do(ctx, append(args, argv...)...); nil != err {
+		return fmt.Errorf("redis: failed to refund user rate limit counters: %v", err)
+	}
+
+	return nil
+}
+
+// Ping checks the server is reachable, and the connection credentials are valid.
+func (r *RedisLimiter) Ping(ctx context.Context) error {
+	if _, err := r.client.Do(ctx, "PING"); nil != err {
+		return fmt.Errorf("redis: failed to ping server: %v", err)
+	}
+	return nil
+}
+
+func (r *RedisLimiter) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// envKeyRedisURL is the URL of the server the tests of RedisLimiter also run against, which are skipped if it's unset.
+const envKeyRedisURL = "REDIS_TEST_URL"
+
+// fakeRedis keeps values in memory, and runs the scripts of RedisLimiter by their Go equivalents,
+// one command at a time, as Redis does.
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+func (f *fakeRedis) handle(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "EVAL":
+		n, _ := strconv.Atoi(args[2])
+		keys, argv := args[3:3+n], args[3+n:]
+		switch args[1] {
+		case takeScript:
+			return f.take(keys, argv)
+		case refundScript:
+			f.refund(keys, argv)
+			return ":0\r\n"
+		}
+		return "-NOSCRIPT unknown script\r\n"
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+// take mirrors takeScript. Expiry is left out, as keys of replenished windows are equivalent to missing ones.
+func (f *fakeRedis) take(keys, argv []string) string {
+	arg := func(i int) int64 {
+		v, _ := strconv.ParseInt(argv[i], 10, 64)
+		return v
+	}
+	now := arg(0)
+	tats := make([]int64, len(keys))
+	allowed := true
+	for i, key := range keys {
+		tat, ok := f.values[key]
+		if !ok || tat < now {
+			tat = now
+		}
+		tats[i] = tat
+		if now+arg(3*i+2)-tat < arg(3*i+1) {
+			allowed = false
+		}
+	}
+	if allowed {
+		for i, key := range keys {
+			if argv[3*i+3] != "1" {
+				continue
+			}
+			tat := tats[i] + arg(3*i+1)
+			if capped := now + arg(3*i+2); tat > capped {
+				tat = capped
+			}
+			f.values[key] = tat
+		}
+	}
+
+	reply := "*" + strconv.Itoa(len(tats)) + "\r\n"
+	for _, tat := range tats {
+		reply += ":" + strconv.FormatInt(tat, 10) + "\r\n"
+	}
+	return reply
+}
+
+// refund mirrors refundScript.
+func (f *fakeRedis) refund(keys, argv []string) {
+	now, _ := strconv.ParseInt(argv[0], 10, 64)
+	for i, key := range keys {
+		interval, _ := strconv.ParseInt(argv[i+1], 10, 64)
+		tat, ok := f.values[key]
+		if !ok {
+			tat = now
+		}
+		if tat -= interval; tat > now {
+			f.values[key] = tat
+		} else {
+			delete(f.values, key)
+		}
+	}
+}
+
+func TestRedisLimiter(t *testing.T) {
+	testLimiter(t, func(t *testing.T, policy Policy) Limiter {
+		f := &fakeRedis{values: map[string]int64{}}
+		s := newFakeServer(t, f.handle)
+		l, err := NewRedis("redis://"+s.addr, policy)
+		if nil != err {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		return l
+	})
+}
+
+// TestRedisLimiterServer runs the scripts themselves, which the fake server only mirrors.
+func TestRedisLimiterServer(t *testing.T) {
+	rawURL := os.Getenv(envKeyRedisURL)
+	if rawURL == "" {
+		t.Skipf("%s is not set", envKeyRedisURL)
+	}
+	testLimiter(t, func(t *testing.T, policy Policy) Limiter {
+		l, err := NewRedis(rawURL, policy)
+		if nil != err {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		// Every test gets its own keys, which expire once their windows are replenished.
+		var suffix [6]byte
+		if _, err := rand.Read(suffix[:]); nil != err {
+			t.Fatal(err)
+		}
+		l.prefix = "ratelimit-test-" + hex.EncodeToString(suffix[:])
+		if err := l.Ping(context.Background()); nil != err {
+			t.Fatal(err)
+		}
+		return l
+	})
+}
@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// respClient is a minimal client for the Redis serialization protocol (RESP2).
+// It holds a single connection, which is re-dialed after any I/O error.
+type respClient struct {
+	addr     string
+	username string
+	password string
+	database int
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+type respError string
+
+func (e respError) Error() string {
+	return "resp: " + string(e)
+}
+
+// newRESPClient parses URLs in redis://[[username]:password@]host[:port][/database] format.
+func newRESPClient(rawURL string) (*respClient, error) {
+	u, err := url.Parse(rawURL)
+	if nil != err {
+		return nil, fmt.Errorf("resp: failed to parse url: %v", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("resp: unsupported url scheme '%s'", u.Scheme)
+	}
+	c := &respClient{addr: u.Host, timeout: 5 * time.Second}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		c.database, err = strconv.Atoi(path)
+		if nil != err {
+			return nil, fmt.Errorf("resp: invalid database number '%s'", path)
+		}
+	}
+
+	return c, nil
+}
+
+// Do sends a command and returns its reply, which is either nil, a string, an int64, or a slice of replies.
+func (c *respClient) Do(ctx context.Context, args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(ctx); nil != err {
+			return nil, err
+		}
+	}
+	reply, err := c.roundTrip(ctx, args)
+	if nil != err {
+		var replyErr respError
+		if !errors.As(err, &replyErr) {
+			c.conn.Close()
+			c.conn = nil
+		}
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+func (c *respClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *respClient) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if nil != err {
+		return fmt.Errorf("resp: failed to connect to %s: %v", c.addr, err)
+	}
+	c.conn, c.rd = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []string{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.password})
+		}
+	}
+	if c.database != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.database)})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(ctx, args); nil != err {
+			c.conn.Close()
+			c.conn = nil
+			return fmt.Errorf("resp: failed to execute %s on connect: %v", args[0], err)
+		}
+	}
+
+	return nil
+}
+
+func (c *respClient) roundTrip(ctx context.Context, args []string) (any, error) {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); nil != err {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := io.WriteString(c.conn, b.String()); nil != err {
+		return nil, fmt.Errorf("resp: failed to write command: %v", err)
+	}
+
+	return c.readReply()
+}
+
+func (c *respClient) readReply() (any, error) {
+	line, err := c.rd.ReadString('\n')
+	if nil != err {
+		return nil, fmt.Errorf("resp: failed to read reply: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("resp: empty reply line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if nil != err {
+			return nil, fmt.Errorf("resp: invalid bulk string length '%s'", line[1:])
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.rd, buf); nil != err {
+			return nil, fmt.Errorf("resp: failed to read bulk string: %v", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if nil != err {
+			return nil, fmt.Errorf("resp: invalid array length '%s'", line[1:])
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.readReply(); nil != err {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("resp: unexpected reply type '%c'", line[0])
+	}
+}
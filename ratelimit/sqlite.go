@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+// SQLiteLimiter keeps theoretical arrival times in the users_rate_limit table.
+type SQLiteLimiter struct {
+	db     *sql.DB
+	policy Policy
+}
+
+func NewSQLite(db *sql.DB, policy Policy) *SQLiteLimiter {
+	return &SQLiteLimiter{
+		db:     db,
+		policy: policy,
+	}
+}
+
+func (r *SQLiteLimiter) Take(ctx context.Context, userID pseudonym.ID, outcomes ...Outcome) (Result, error) {
+	var res Result
+	err := r.update(ctx, userID, func(tats arrivals, now int64) arrivals {
+		var taken arrivals
+		res, taken = take(r.policy, tats, now, outcomes)
+		return taken
+	})
+	if nil != err {
+		return Result{}, err
+	}
+
+	return res, nil
+}
+
+func (r *SQLiteLimiter) Refund(ctx context.Context, userID pseudonym.ID, outcome Outcome) error {
+	return r.update(ctx, userID, func(tats arrivals, now int64) arrivals {
+		return refund(r.policy, tats, now, outcome)
+	})
+}
+
+// update passes the arrival times of the user to fn, and stores the ones it returns, in a single write transaction.
+func (r *SQLiteLimiter) update(ctx context.Context, userID pseudonym.ID, fn func(tats arrivals, now int64) arrivals) error {
+	return db.Retry(ctx, func() error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if nil != err {
+			return db.WrapErr(err, "failed to begin user rate limit transaction")
+		}
+		defer tx.Rollback()
+
+		now := time.Now().UTC().UnixMilli()
+		// Deleting first takes the write lock before anything is read, so that concurrent transactions wait for each
+		// other by the busy timeout, rather than failing to upgrade their read locks. Replenished windows are
+		// equivalent to missing ones anyway.
+		_, err = table.UsersRateLimit.
+			DELETE().
+			WHERE(
+				table.UsersRateLimit.TheUserID.EQ(sqlite.Int64(int64(userID))).
+					AND(table.UsersRateLimit.TheoreticalArrivalMs.LT_EQ(sqlite.Int64(now))),
+			).
+			ExecContext(ctx, tx)
+		if nil != err {
+			return db.WrapErr(err, "failed to delete replenished user rate limit counters")
+		}
+		var rows []model.UsersRateLimit
+		err = table.UsersRateLimit.
+			SELECT(table.UsersRateLimit.AllColumns).
+			WHERE(table.UsersRateLimit.TheUserID.EQ(sqlite.Int64(int64(userID)))).
+			QueryContext(ctx, tx, &rows)
+		if nil != err {
+			return db.WrapErr(err, "failed to query user rate limit counters")
+		}
+		tats := arrivals{}
+		for _, row := range rows {
+			tats.set(Outcome(row.Outcome), row.WindowSeconds, row.TheoreticalArrivalMs)
+		}
+
+		if updated := rowsOf(userID, fn(tats, now)); len(updated) > 0 {
+			_, err = table.UsersRateLimit.
+				INSERT(table.UsersRateLimit.AllColumns).
+				MODELS(updated).
+				ON_CONFLICT(table.UsersRateLimit.TheUserID, table.UsersRateLimit.Outcome, table.UsersRateLimit.WindowSeconds).
+				DO_UPDATE(sqlite.SET(table.UsersRateLimit.TheoreticalArrivalMs.SET(table.UsersRateLimit.EXCLUDED.TheoreticalArrivalMs))).
+				ExecContext(ctx, tx)
+			if nil != err {
+				return db.WrapErr(err, "failed to update user rate limit counters")
+			}
+		}
+		if err := tx.Commit(); nil != err {
+			return db.WrapErr(err, "failed to commit user rate limit transaction")
+		}
+
+		return nil
+	})
+}
+
+// rowsOf returns the rows of the arrival times of the user.
+func rowsOf(userID pseudonym.ID, tats arrivals) []model.UsersRateLimit {
+	var rows []model.UsersRateLimit
+	for outcome, windows := range tats {
+		for windowSeconds, tat := range windows {
+			rows = append(rows, model.UsersRateLimit{
+				TheUserID:            int64(userID),
+				Outcome:              string(outcome),
+				WindowSeconds:        windowSeconds,
+				TheoreticalArrivalMs: tat,
+			})
+		}
+	}
+
+	return rows
+}
@@ -0,0 +1,19 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/dbtest"
+)
+
+func TestSQLiteLimiter(t *testing.T) {
+	testLimiter(t, func(t *testing.T, policy Policy) Limiter {
+		return NewSQLite(dbtest.SQLite(t), policy)
+	})
+}
+
+func TestSQLiteLimiterSharedFile(t *testing.T) {
+	testLimiter(t, func(t *testing.T, policy Policy) Limiter {
+		return NewSQLite(dbtest.SQLiteFile(t), policy)
+	})
+}
@@ -0,0 +1,161 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-jet/jet/v2/sqlite"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/table"
+)
+
+type Policy struct {
+	// SubmitterMaxAge is the age after which domains, and hosts, are no longer linked to their submitter.
+	SubmitterMaxAge time.Duration
+}
+
+type Report struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	DryRun     bool
+	// RateLimitRowsDeleted is the number of rate limit windows which are fully replenished,
+	// hence carry no information the limiter needs anymore.
+	RateLimitRowsDeleted int64
+	DomainsUnlinked      int64
+	HostsUnlinked        int64
+}
+
+// Purge deletes stale rate limit rows, and unlinks old domains, and hosts, from their submitters.
+// Each run is recorded in the purge_runs table. In dry run mode, nothing is changed,
+// and the report holds the number of rows that would have been affected.
+func Purge(ctx context.Context, dbConn *sql.DB, policy Policy, dryRun bool) (Report, error) {
+	startedAt := time.Now().UTC()
+	report := Report{StartedAt: startedAt, DryRun: dryRun}
+
+	staleRateLimit := table.UsersRateLimit.TheoreticalArrivalMs.LT_EQ(sqlite.Int64(startedAt.UnixMilli()))
+	cutoff := sqlite.Int64(startedAt.Add(-policy.SubmitterMaxAge).Unix())
+	oldDomains := sqlite.AND(table.Domains.CreatedByID.IS_NOT_NULL(), table.Domains.CreatedTs.LT(cutoff))
+	oldHosts := sqlite.AND(table.Hosts.CreatedByID.IS_NOT_NULL(), table.Hosts.CreatedTs.LT(cutoff))
+
+	if dryRun {
+		var err error
+		if report.RateLimitRowsDeleted, err = count(ctx, dbConn, table.UsersRateLimit, staleRateLimit); nil != err {
+			return Report{}, db.WrapErr(err, "failed to count stale rate limit rows")
+		}
+		if report.DomainsUnlinked, err = count(ctx, dbConn, table.Domains, oldDomains); nil != err {
+			return Report{}, db.WrapErr(err, "failed to count domains linked to submitters")
+		}
+		if report.HostsUnlinked, err = count(ctx, dbConn, table.Hosts, oldHosts); nil != err {
+			return Report{}, db.WrapErr(err, "failed to count hosts linked to submitters")
+		}
+		report.FinishedAt = time.Now().UTC()
+		return report, nil
+	}
+
+	err := db.Retry(ctx, func() error {
+		var err error
+		report, err = purge(ctx, dbConn, startedAt, staleRateLimit, oldDomains, oldHosts)
+		return err
+	})
+	if nil != err {
+		return Report{}, err
+	}
+
+	return report, nil
+}
+
+// purge runs the purge in a single transaction, which is retried as a whole when the database is busy.
+func purge(ctx context.Context, dbConn *sql.DB, startedAt time.Time, staleRateLimit, oldDomains, oldHosts sqlite.BoolExpression) (Report, error) {
+	report := Report{StartedAt: startedAt}
+
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if nil != err {
+		return Report{}, db.WrapErr(err, "failed to begin purge transaction")
+	}
+	defer tx.Rollback()
+
+	res, err := table.UsersRateLimit.DELETE().WHERE(staleRateLimit).ExecContext(ctx, tx)
+	if nil != err {
+		return Report{}, db.WrapErr(err, "failed to delete stale rate limit rows")
+	}
+	if report.RateLimitRowsDeleted, err = res.RowsAffected(); nil != err {
+		return Report{}, fmt.Errorf("db: failed to get number of deleted rate limit rows: %v", err)
+	}
+
+	res, err = table.Domains.
+		UPDATE(table.Domains.CreatedByID).
+		SET(sqlite.NULL).
+		WHERE(oldDomains).
+		ExecContext(ctx, tx)
+	if nil != err {
+		return Report{}, db.WrapErr(err, "failed to unlink domains from submitters")
+	}
+	if report.DomainsUnlinked, err = res.RowsAffected(); nil != err {
+		return Report{}, fmt.Errorf("db: failed to get number of unlinked domains: %v", err)
+	}
+
+	res, err = table.Hosts.
+		UPDATE(table.Hosts.CreatedByID).
+		SET(sqlite.NULL).
+		WHERE(oldHosts).
+		ExecContext(ctx, tx)
+	if nil != err {
+		return Report{}, db.WrapErr(err, "failed to unlink hosts from submitters")
+	}
+	if report.HostsUnlinked, err = res.RowsAffected(); nil != err {
+		return Report{}, fmt.Errorf("db: failed to get number of unlinked hosts: %v", err)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	_, err = table.PurgeRuns.
+		INSERT(table.PurgeRuns.MutableColumns).
+		MODEL(model.PurgeRuns{
+			StartedTs:            report.StartedAt.Unix(),
+			FinishedTs:           report.FinishedAt.Unix(),
+			RateLimitRowsDeleted: report.RateLimitRowsDeleted,
+			DomainsUnlinked:      report.DomainsUnlinked,
+			HostsUnlinked:        report.HostsUnlinked,
+		}).
+		ExecContext(ctx, tx)
+	if nil != err {
+		return Report{}, db.WrapErr(err, "failed to record purge run")
+	}
+
+	if err := tx.Commit(); nil != err {
+		return Report{}, db.WrapErr(err, "failed to commit purge transaction")
+	}
+
+	return report, nil
+}
+
+// Run purges every interval until ctx is done.
+func Run(ctx context.Context, dbConn *sql.DB, policy Policy, interval time.Duration, onReport func(Report), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := Purge(ctx, dbConn, policy, false)
+			if nil != err {
+				onError(err)
+				continue
+			}
+			onReport(report)
+		}
+	}
+}
+
+func count(ctx context.Context, dbConn *sql.DB, from sqlite.ReadableTable, where sqlite.BoolExpression) (int64, error) {
+	query, args := sqlite.SELECT(sqlite.COUNT(sqlite.STAR)).FROM(from).WHERE(where).Sql()
+	var n int64
+	if err := dbConn.QueryRowContext(ctx, query, args...).Scan(&n); nil != err {
+		return 0, err
+	}
+	return n, nil
+}
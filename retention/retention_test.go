@@ -0,0 +1,84 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db/dbtest"
+)
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	now := time.Now().UTC()
+	old, recent := now.Add(-48*time.Hour).Unix(), now.Add(-time.Hour).Unix()
+	const submitter = 1
+	seed := []struct {
+		query string
+		args  []any
+	}{
+		{"INSERT INTO domains (domain, created_ts, created_by_id) VALUES (?, ?, ?)", []any{"old.ir", old, submitter}},
+		{"INSERT INTO domains (domain, created_ts, created_by_id) VALUES (?, ?, ?)", []any{"recent.ir", recent, submitter}},
+		{"INSERT INTO hosts (host, domain, created_ts, created_by_id) VALUES (?, ?, ?, ?)", []any{"old.ir", "old.ir", old, submitter}},
+		{"INSERT INTO hosts (host, domain, created_ts, created_by_id) VALUES (?, ?, ?, ?)", []any{"cdn.old.ir", "old.ir", recent, submitter}},
+		{"INSERT INTO hosts (host, domain, created_ts, created_by_id) VALUES (?, ?, ?, ?)", []any{"recent.ir", "recent.ir", recent, submitter}},
+		{"INSERT INTO users_rate_limit (the_user_id, outcome, window_seconds, theoretical_arrival_ms) VALUES (?, ?, ?, ?)", []any{submitter, "succeeded", 60, now.Add(-time.Minute).UnixMilli()}},
+		{"INSERT INTO users_rate_limit (the_user_id, outcome, window_seconds, theoretical_arrival_ms) VALUES (?, ?, ?, ?)", []any{submitter, "failed", 60, now.Add(time.Minute).UnixMilli()}},
+	}
+	for _, s := range seed {
+		if _, err := conn.ExecContext(ctx, s.query, s.args...); nil != err {
+			t.Fatalf("failed to seed database: %v", err)
+		}
+	}
+	policy := Policy{SubmitterMaxAge: 24 * time.Hour}
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := Purge(ctx, conn, policy, dryRun)
+		if nil != err {
+			t.Fatalf("Purge(dryRun: %v) failed: %v", dryRun, err)
+		}
+		got := [3]int64{report.RateLimitRowsDeleted, report.DomainsUnlinked, report.HostsUnlinked}
+		if want := [3]int64{1, 1, 1}; got != want {
+			t.Errorf("Purge(dryRun: %v) deleted, and unlinked, %v rows, want %v", dryRun, got, want)
+		}
+	}
+
+	linked := func(query string) []string {
+		t.Helper()
+		rows, err := conn.QueryContext(ctx, query)
+		if nil != err {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); nil != err {
+				t.Fatal(err)
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+	if got := linked("SELECT domain FROM domains WHERE created_by_id IS NOT NULL ORDER BY domain"); len(got) != 1 || got[0] != "recent.ir" {
+		t.Errorf("domains linked to their submitter = %v, want [recent.ir]", got)
+	}
+	if got := linked("SELECT host FROM hosts WHERE created_by_id IS NOT NULL ORDER BY host"); len(got) != 2 || got[0] != "cdn.old.ir" || got[1] != "recent.ir" {
+		t.Errorf("hosts linked to their submitter = %v, want [cdn.old.ir recent.ir]", got)
+	}
+	if got := linked("SELECT outcome FROM users_rate_limit"); len(got) != 1 || got[0] != "failed" {
+		t.Errorf("rate limit rows = %v, want [failed]", got)
+	}
+
+	report, err := Purge(ctx, conn, policy, false)
+	if nil != err {
+		t.Fatalf("Purge() failed: %v", err)
+	}
+	if report.RateLimitRowsDeleted != 0 || report.DomainsUnlinked != 0 || report.HostsUnlinked != 0 {
+		t.Errorf("Purge() of a purged database = %+v, want nothing changed", report)
+	}
+	if runs := linked("SELECT CAST(hosts_unlinked AS TEXT) FROM purge_runs ORDER BY id"); len(runs) != 2 || runs[0] != "1" || runs[1] != "0" {
+		t.Errorf("recorded purge runs unlinked %v hosts, want [1 0]", runs)
+	}
+}
@@ -0,0 +1,175 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/z4x7k/iran-domains-tg-bot/ratelimit"
+)
+
+// Telegram Bot API limits, see: https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+const (
+	GlobalMessagesPerSecond = 30
+	PrivateChatInterval     = time.Second
+	GroupChatInterval       = 3 * time.Second
+	MaxQueueDelay           = 30 * time.Second
+	MaxRetries              = 3
+)
+
+var ErrQueueFull = errors.New("outgoing message queue for chat is full")
+
+// The bot library reports non-200 responses by their status code, and body, e.g. `unexpected response statusCode 429
+// for method sendMessage, {..."parameters":{"retry_after":5}}`, and failed ones by their description,
+// e.g. `error response from telegram for method sendMessage, Too Many Requests: retry after 5`.
+var (
+	tooManyRequestsRegexp = regexp.MustCompile(`statusCode 429|Too Many Requests`)
+	retryAfterRegexp      = regexp.MustCompile(`"retry_after"\s*:\s*(\d+)|retry after (\d+)`)
+)
+
+// Scheduler paces outgoing requests to stay within Telegram's global and per-chat limits.
+// Callers are queued by reserving the next free slot of their chat, and block until it comes.
+type Scheduler struct {
+	global *ratelimit.TokenBucket
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+	chatNext    map[string]time.Time
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		global:   ratelimit.NewTokenBucket(GlobalMessagesPerSecond, GlobalMessagesPerSecond),
+		chatNext: map[string]time.Time{},
+	}
+}
+
+func (s *Scheduler) SendMessage(ctx context.Context, b *bot.Bot, params *bot.SendMessageParams) (*models.Message, error) {
+	var msg *models.Message
+	err := s.Do(ctx, params.ChatID, func(ctx context.Context) error {
+		var err error
+		msg, err = b.SendMessage(ctx, params)
+		return err
+	})
+	return msg, err
+}
+
+// Do runs fn in the next free slot of chatID, retrying it after the delay
+// requested by Telegram when it responds with 429 Too Many Requests.
+func (s *Scheduler) Do(ctx context.Context, chatID any, fn func(ctx context.Context) error) error {
+	chat := fmt.Sprint(chatID)
+	for attempt := 0; ; attempt++ {
+		delay, err := s.reserve(chat)
+		if nil != err {
+			return err
+		}
+		if err := sleep(ctx, delay); nil != err {
+			return err
+		}
+		if err := s.global.Wait(ctx); nil != err {
+			return err
+		}
+
+		err = fn(ctx)
+		if nil == err {
+			return nil
+		}
+		retryAfter, ok := parseRetryAfter(err)
+		if !ok || attempt >= MaxRetries {
+			return err
+		}
+		s.pause(chat, retryAfter)
+	}
+}
+
+func (s *Scheduler) reserve(chat string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if len(s.chatNext) > 1024 {
+		for k, v := range s.chatNext {
+			if v.Before(now) {
+				delete(s.chatNext, k)
+			}
+		}
+	}
+
+	slot := now
+	if next := s.chatNext[chat]; next.After(slot) {
+		slot = next
+	}
+	if s.pausedUntil.After(slot) {
+		slot = s.pausedUntil
+	}
+	delay := slot.Sub(now)
+	if delay > MaxQueueDelay {
+		return 0, ErrQueueFull
+	}
+	s.chatNext[chat] = slot.Add(chatInterval(chat))
+
+	return delay, nil
+}
+
+// pause delays every queued request of chat, and since a 429 may as well be
+// caused by the global limit, briefly holds back all other chats too.
+func (s *Scheduler) pause(chat string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if s.chatNext[chat].Before(until) {
+		s.chatNext[chat] = until
+	}
+	if global := time.Now().Add(d / 2); s.pausedUntil.Before(global) {
+		s.pausedUntil = global
+	}
+}
+
+// chatInterval returns the minimum interval between two messages to chat.
+// Group, supergroup and channel identifiers are either negative, or usernames.
+func chatInterval(chat string) time.Duration {
+	if strings.HasPrefix(chat, "-") || strings.HasPrefix(chat, "@") {
+		return GroupChatInterval
+	}
+	return PrivateChatInterval
+}
+
+// parseRetryAfter returns the delay requested by a 429 Too Many Requests error, which is a second if it has none,
+// and whether err is one.
+func parseRetryAfter(err error) (time.Duration, bool) {
+	if !tooManyRequestsRegexp.MatchString(err.Error()) {
+		return 0, false
+	}
+	m := retryAfterRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return time.Second, true
+	}
+	seconds, convErr := strconv.Atoi(m[1] + m[2])
+	if nil != convErr {
+		return time.Second, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    string
+		want   time.Duration
+		wantOK bool
+	}{
+		{
+			name:   "status code and body",
+			err:    `unexpected response statusCode 429 for method sendMessage, {"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`,
+			want:   7 * time.Second,
+			wantOK: true,
+		},
+		{
+			name:   "description",
+			err:    "error response from telegram for method sendMessage, Too Many Requests: retry after 12",
+			want:   12 * time.Second,
+			wantOK: true,
+		},
+		{name: "without delay", err: "unexpected response statusCode 429 for method sendMessage, ", want: time.Second, wantOK: true},
+		{name: "other status code", err: `unexpected response statusCode 400 for method sendMessage, {"ok":false,"error_code":400}`},
+		{name: "other error", err: "error response from telegram for method sendMessage, Bad Request: chat not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(errors.New(tt.err))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.err, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSchedulerReserve(t *testing.T) {
+	s := New()
+	within := func(got, want time.Duration) bool {
+		return got >= want-100*time.Millisecond && got <= want
+	}
+
+	for i, want := range []time.Duration{0, PrivateChatInterval, 2 * PrivateChatInterval} {
+		if got, err := s.reserve("42"); nil != err || !within(got, want) {
+			t.Fatalf("reserve(private chat) #%d = %v, %v, want %v", i, got, err, want)
+		}
+	}
+	for i, want := range []time.Duration{0, GroupChatInterval} {
+		if got, err := s.reserve("-100"); nil != err || !within(got, want) {
+			t.Fatalf("reserve(group chat) #%d = %v, %v, want %v", i, got, err, want)
+		}
+	}
+
+	var err error
+	// Slots up to the maximum delay are reserved, and the next one is refused.
+	for i := 0; i < int(MaxQueueDelay/PrivateChatInterval)+2 && nil == err; i++ {
+		_, err = s.reserve("43")
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("reserve() past the maximum queue delay = %v, want %v", err, ErrQueueFull)
+	}
+
+	// A 429 holds back the chat for the whole delay, and other chats for half of it.
+	s.pause("44", 10*time.Second)
+	if got, err := s.reserve("44"); nil != err || !within(got, 10*time.Second) {
+		t.Fatalf("reserve() of a paused chat = %v, %v, want 10s", got, err)
+	}
+	if got, err := s.reserve("45"); nil != err || !within(got, 5*time.Second) {
+		t.Fatalf("reserve() of another chat = %v, %v, want 5s", got, err)
+	}
+}
+
+func TestSchedulerDoRetries(t *testing.T) {
+	ctx := context.Background()
+	tooManyRequests := errors.New("error response from telegram for method sendMessage, Too Many Requests: retry after 0")
+
+	t.Run("gives up after the maximum retries", func(t *testing.T) {
+		var calls int
+		err := New().Do(ctx, 1, func(context.Context) error {
+			calls++
+			return tooManyRequests
+		})
+		if err != tooManyRequests || calls != MaxRetries+1 {
+			t.Fatalf("Do() = %v after %d calls, want %v after %d", err, calls, tooManyRequests, MaxRetries+1)
+		}
+	})
+
+	t.Run("doesn't retry other errors", func(t *testing.T) {
+		var calls int
+		want := errors.New("error response from telegram for method sendMessage, Forbidden: bot was blocked by the user")
+		err := New().Do(ctx, 2, func(context.Context) error {
+			calls++
+			return want
+		})
+		if err != want || calls != 1 {
+			t.Fatalf("Do() = %v after %d calls, want %v after 1", err, calls, want)
+		}
+	})
+
+	t.Run("retries 429 responses of the bot API", func(t *testing.T) {
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if requests.Add(1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = io.WriteString(w, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":3,"type":"private"}}}`)
+		}))
+		t.Cleanup(srv.Close)
+		b, err := bot.New("token", bot.WithSkipGetMe(), bot.WithServerURL(srv.URL))
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		startedAt := time.Now()
+		msg, err := New().SendMessage(ctx, b, &bot.SendMessageParams{ChatID: 3, Text: "hi"})
+		if nil != err || msg.ID != 1 {
+			t.Fatalf("SendMessage() = %v, %v, want the message", msg, err)
+		}
+		if n := requests.Load(); n != 2 {
+			t.Errorf("server received %d requests, want 2", n)
+		}
+		if took := time.Since(startedAt); took < time.Second {
+			t.Errorf("SendMessage() retried after %v, want the requested second", took)
+		}
+	})
+
+	t.Run("stops waiting once ctx is done", func(t *testing.T) {
+		s := New()
+		s.pause("5", 10*time.Second)
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err := s.Do(ctx, 5, func(context.Context) error { return nil })
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Do() of a paused chat = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/joho/godotenv"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pressly/goose/v3"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+
+	"github.com/z4x7k/iran-domains-tg-bot/backup"
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/store"
+)
+
+// loadEnv loads the .env file given by the env flag, and validates the common environment variables.
+func loadEnv(log zerolog.Logger, cliCtx *cli.Context) error {
+	envFilename := cliCtx.String(CLIRunCommandEnvFileFlag)
+	if envFilename == "" {
+		envFilename = ".env"
+	}
+
+	if err := godotenv.Load(envFilename); nil != err {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("env: unexpected error while loading environment variables from .env file")
+		}
+		log.Warn().Msg(".env file not found")
+	}
+
+	tz, ok := os.LookupEnv("TZ")
+	if !ok || tz != "UTC" {
+		return errors.New("env: TZ environment variable must be set to UTC")
+	}
+
+	return nil
+}
+
+func dbFilename(cliCtx *cli.Context) string {
+	if name := cliCtx.String(CLIRunCommandDBFileFlag); name != "" {
+		return name
+	}
+	return "domains.db"
+}
+
+// openDatabase opens the database file given by the db flag, and executes pragmas.
+// The returned function closes the connection.
+func openDatabase(ctx context.Context, log zerolog.Logger, cliCtx *cli.Context) (*sql.DB, func(), error) {
+	busyTimeout := DefaultDBBusyTimeout
+	if val, ok := os.LookupEnv(EnvKeyDBBusyTimeout); ok && val != "" {
+		parsed, err := time.ParseDuration(val)
+		if nil != err || parsed < 0 {
+			return nil, nil, fmt.Errorf("env: '%s' must be a non-negative duration", EnvKeyDBBusyTimeout)
+		}
+		busyTimeout = parsed
+	}
+	// The lock is held for as long as the database is open, so that it isn't restored under the bot.
+	unlock, err := backup.Lock(dbFilename(cliCtx))
+	if nil != err {
+		return nil, nil, fmt.Errorf("db: %v", err)
+	}
+	// The busy timeout is set by the driver on every new connection of the pool.
+	dsn := fmt.Sprintf("%s?_busy_timeout=%d", dbFilename(cliCtx), busyTimeout.Milliseconds())
+	dbConn, err := sql.Open("sqlite3", dsn)
+	if nil != err {
+		unlock()
+		return nil, nil, fmt.Errorf("db: failed to open database: %v", err)
+	}
+	closeDB := func() {
+		log.Info().Msg("closing database connection")
+		if err := dbConn.Close(); nil != err {
+			log.Error().Err(err).Msg("failed to close database connection")
+		}
+		unlock()
+	}
+	if err := dbConn.PingContext(ctx); nil != err {
+		closeDB()
+		return nil, nil, fmt.Errorf("db: failed to ping database connection: %v", err)
+	}
+	sqliteLibVersion, sqliteLibVersionNumber, _ := sqlite3.Version()
+	log.Info().Str("lib_version", sqliteLibVersion).Int("lib_version_number", sqliteLibVersionNumber).Msg("successfully connected to sqlite database")
+	if err := db.ExecPragmas(ctx, dbConn); nil != err {
+		closeDB()
+		return nil, nil, fmt.Errorf("db: unable to execute database pragmas: %v", err)
+	}
+	log.Info().Msg("successfully executed database pragmas")
+
+	return dbConn, closeDB, nil
+}
+
+// newBot creates a bot client using the token, and the optional proxy, from the environment. If wrapClient isn't nil,
+// the bot sends its requests through the client it returns, e.g. to count failed requests.
+func newBot(wrapClient func(bot.HttpClient) bot.HttpClient, opts ...bot.Option) (*bot.Bot, error) {
+	httpTransport := http.Transport{IdleConnTimeout: 10 * time.Second, ResponseHeaderTimeout: 30 * time.Second}
+	httpClient := http.Client{Timeout: time.Second * 35, Transport: &httpTransport}
+	proxyURL, ok := os.LookupEnv(EnvKeyBotHTTPProxyURL)
+	if ok && proxyURL != "" {
+		httpProxyURL, err := url.Parse(proxyURL)
+		if nil != err {
+			return nil, fmt.Errorf("proxy: failed to parse bot http proxy url: %v", err)
+		}
+		httpTransport.Proxy = http.ProxyURL(httpProxyURL)
+	}
+
+	var client bot.HttpClient = &httpClient
+	if nil != wrapClient {
+		client = wrapClient(client)
+	}
+	opts = append([]bot.Option{
+		bot.WithCheckInitTimeout(5 * time.Second),
+		bot.WithHTTPClient(25*time.Second, client),
+	}, opts...)
+
+	token, ok := os.LookupEnv(EnvKeyBotToken)
+	if !ok {
+		return nil, fmt.Errorf("env: required environment variable '%s' is not set", EnvKeyBotToken)
+	}
+
+	b, err := bot.New(token, opts...)
+	if nil != err {
+		return nil, fmt.Errorf("bot: failed to initialize bot instance: %v", err)
+	}
+
+	return b, nil
+}
+
+// openDatabaseFromEnv opens the postgres database given by the environment if set, otherwise the sqlite database file,
+// and reports whether it's postgres. The returned function closes the connection.
+func openDatabaseFromEnv(ctx context.Context, log zerolog.Logger, cliCtx *cli.Context) (*sql.DB, bool, func(), error) {
+	postgresURL, ok := os.LookupEnv(EnvKeyPostgresURL)
+	if !ok || postgresURL == "" {
+		dbConn, closeDB, err := openDatabase(ctx, log, cliCtx)
+		return dbConn, false, closeDB, err
+	}
+
+	dbConn, err := sql.Open("postgres", postgresURL)
+	if nil != err {
+		return nil, false, nil, fmt.Errorf("db: failed to open postgres database: %v", err)
+	}
+	closeDB := func() {
+		log.Info().Msg("closing database connection")
+		if err := dbConn.Close(); nil != err {
+			log.Error().Err(err).Msg("failed to close database connection")
+		}
+	}
+	if err := dbConn.PingContext(ctx); nil != err {
+		closeDB()
+		return nil, false, nil, fmt.Errorf("db: failed to ping postgres database connection: %v", err)
+	}
+	log.Info().Msg("successfully connected to postgres database")
+
+	return dbConn, true, closeDB, nil
+}
+
+// openStore opens the database, migrates it unless autoMigrate is unset, and verifies its schema version matches the binary.
+// The returned *sql.DB is the sqlite connection used by the features which are specific to sqlite, and is nil for postgres.
+// The returned function closes the connection.
+func openStore(ctx context.Context, log zerolog.Logger, cliCtx *cli.Context, autoMigrate bool) (store.Store, *sql.DB, func(), error) {
+	dbConn, isPostgres, closeDB, err := openDatabaseFromEnv(ctx, log, cliCtx)
+	if nil != err {
+		return nil, nil, nil, err
+	}
+	if err := setupMigrations(isPostgres, goose.NopLogger()); nil != err {
+		closeDB()
+		return nil, nil, nil, err
+	}
+	if autoMigrate {
+		if err := goose.UpContext(ctx, dbConn, migrationsDir); nil != err {
+			closeDB()
+			return nil, nil, nil, fmt.Errorf("db: failed to execute goose migrations: %v", err)
+		}
+		log.Info().Msg("executed database migrations")
+	}
+	if err := checkSchemaVersion(ctx, dbConn); nil != err {
+		closeDB()
+		return nil, nil, nil, err
+	}
+
+	if isPostgres {
+		return store.NewPostgres(dbConn), nil, closeDB, nil
+	}
+	return store.NewSQLite(dbConn), dbConn, closeDB, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+	"github.com/z4x7k/iran-domains-tg-bot/stats"
+)
+
+const (
+	// statsTopSubmitters is the number of top contributors listed in the statistics.
+	statsTopSubmitters = 10
+	// publicNameMaxLength is the maximum number of characters of the names users are shown by among the top contributors.
+	publicNameMaxLength = 32
+)
+
+func statsCacheTTLFromEnv() (time.Duration, error) {
+	ttl := DefaultStatsCacheTTL
+	if val, ok := os.LookupEnv(EnvKeyStatsCacheTTL); ok && val != "" {
+		parsed, err := time.ParseDuration(val)
+		if nil != err || parsed < 0 {
+			return 0, fmt.Errorf("'%s' must be a non-negative duration", EnvKeyStatsCacheTTL)
+		}
+		ttl = parsed
+	}
+
+	return ttl, nil
+}
+
+// statsSummaryIntervalFromEnv returns the interval of the summaries posted to the publish chat, and whether they're enabled.
+func statsSummaryIntervalFromEnv() (time.Duration, bool, error) {
+	val, ok := os.LookupEnv(EnvKeyStatsSummaryInterval)
+	if !ok || val == "" {
+		return 0, false, nil
+	}
+	interval, err := time.ParseDuration(val)
+	if nil != err || interval <= 0 {
+		return 0, false, fmt.Errorf("'%s' must be a positive duration", EnvKeyStatsSummaryInterval)
+	}
+
+	return interval, true, nil
+}
+
+// pseudonymLabel returns the short label submitters without a public name are listed by, which is stable, but can't be
+// linked to their Telegram account without the pseudonymization key.
+func pseudonymLabel(id pseudonym.ID) string {
+	return fmt.Sprintf("%06x", uint64(id)>>40)
+}
+
+// statsText returns the domain counts, and the top contributors, of the summary, with the user marked among them, if any.
+func (h *Handler) statsText(lang i18n.Lang, summary stats.Summary, user *pseudonym.ID) string {
+	text := h.text(lang, i18n.MsgStatsDomains, i18n.Params{
+		"Total":    summary.Domains.Total,
+		"Today":    summary.Domains.Today,
+		"ThisWeek": summary.Domains.ThisWeek,
+	})
+	if len(summary.Top) == 0 {
+		return text
+	}
+	submitters := make([]i18n.Params, 0, len(summary.Top))
+	for i, s := range summary.Top {
+		submitters = append(submitters, i18n.Params{
+			"Rank":  i + 1,
+			"Name":  s.Name,
+			"ID":    pseudonymLabel(s.ID),
+			"Hosts": s.Hosts,
+			"You":   nil != user && *user == s.ID,
+		})
+	}
+	return text + "\n\n" + h.text(lang, i18n.MsgStatsTop, i18n.Params{"Submitters": submitters})
+}
+
+func (h *Handler) handleStatsCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscard(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
+	chatID := update.Message.Chat.ID
+	userID := h.pseudonymizer.ID(update.Message.From.ID)
+
+	summary, err := h.stats.Summary(ctx)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to compute statistics")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	hosts, err := h.store.CountSubmissions(ctx, userID)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to count user submissions")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	name, err := h.store.PublicName(ctx, userID)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to query user public name")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+
+	text := h.statsText(lang, summary, &userID) + "\n\n" + h.text(lang, i18n.MsgStatsMine, i18n.Params{"Hosts": hosts, "Name": name})
+	h.replyText(ctx, b, chatID, text, markup.MarkdownV2.ParseMode())
+}
+
+// handlePublicNameCommand toggles whether the user is shown among the top contributors by their first name, or by their pseudonym,
+// which is the default, as names are only stored for users who opt in.
+func (h *Handler) handlePublicNameCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscard(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
+	chatID := update.Message.Chat.ID
+	userID := h.pseudonymizer.ID(update.Message.From.ID)
+
+	name, err := h.store.PublicName(ctx, userID)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to query user public name")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	if name == "" {
+		name = markup.Truncate(update.Message.From.FirstName, publicNameMaxLength)
+	} else {
+		name = ""
+	}
+	if err := h.store.SetPublicName(ctx, userID, name); nil != err {
+		log.Error().Err(err).Msg("failed to update user public name")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	log.Info().Bool("public", name != "").Msg("updated user public name")
+
+	if name == "" {
+		h.replyText(ctx, b, chatID, h.text(lang, i18n.MsgPublicNameUnset, nil), markup.MarkdownV2.ParseMode())
+		return
+	}
+	h.replyText(ctx, b, chatID, h.text(lang, i18n.MsgPublicNameSet, i18n.Params{"Name": name}), markup.MarkdownV2.ParseMode())
+}
+
+// postStatsSummary posts the summary to the publish chat in the default language.
+func (h *Handler) postStatsSummary(ctx context.Context, b *bot.Bot, log zerolog.Logger, summary stats.Summary) {
+	text := h.text(h.defaultLang, i18n.MsgStatsSummary, i18n.Params{"At": summary.GeneratedAt.Format("2006-01-02 15:04 UTC")}) +
+		"\n\n" + h.statsText(h.defaultLang, summary, nil)
+	if _, err := h.sender.SendMessage(ctx, b, &bot.SendMessageParams{
+		ChatID:    h.publishChatID,
+		Text:      text,
+		ParseMode: markup.MarkdownV2.ParseMode(),
+	}); nil != err {
+		log.Error().Err(err).Msg("failed to post statistics summary")
+		h.informSupport(ctx, b, err)
+		return
+	}
+	log.Info().Int("domains", summary.Domains.Total).Int("top_submitters", len(summary.Top)).Msg("posted statistics summary")
+}
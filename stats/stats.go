@@ -0,0 +1,93 @@
+// Package stats computes statistics of the listed domains, and their submitters, which are cached, as they're the same for everyone.
+package stats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+)
+
+// Source runs the aggregate queries the statistics are computed by.
+type Source interface {
+	CountDomains(ctx context.Context, day, week time.Time) (db.DomainCounts, error)
+	TopSubmitters(ctx context.Context, limit int) ([]db.Submitter, error)
+}
+
+type Summary struct {
+	GeneratedAt time.Time
+	// Domains counts the domains added since the start of the day, and of the week, which starts on Monday, in UTC.
+	Domains db.DomainCounts
+	// Top are the submitters with the most hosts linked to them, which excludes the hosts unlinked by retention.
+	Top []db.Submitter
+}
+
+// Cache holds the last summary, and computes a new one once it's older than its TTL.
+type Cache struct {
+	source Source
+	ttl    time.Duration
+	top    int
+
+	mu      sync.Mutex
+	summary *Summary
+}
+
+// New returns a cache of summaries of the top submitters, by up to top of them, which are recomputed after ttl.
+func New(source Source, ttl time.Duration, top int) *Cache {
+	return &Cache{source: source, ttl: ttl, top: top}
+}
+
+// Summary returns the cached summary, or a new one if it's expired. Callers wait for a single computation of an expired summary.
+func (c *Cache) Summary(ctx context.Context) (Summary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if nil != c.summary && time.Since(c.summary.GeneratedAt) < c.ttl {
+		return *c.summary, nil
+	}
+	return c.refresh(ctx)
+}
+
+// Run calls onSummary with a new summary every interval until ctx is done.
+func (c *Cache) Run(ctx context.Context, interval time.Duration, onSummary func(Summary), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			summary, err := c.refresh(ctx)
+			c.mu.Unlock()
+			if nil != err {
+				if ctx.Err() != nil {
+					return
+				}
+				onError(err)
+				continue
+			}
+			onSummary(summary)
+		}
+	}
+}
+
+// refresh must be called with mu held.
+func (c *Cache) refresh(ctx context.Context) (Summary, error) {
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	week := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+
+	domains, err := c.source.CountDomains(ctx, day, week)
+	if nil != err {
+		return Summary{}, err
+	}
+	top, err := c.source.TopSubmitters(ctx, c.top)
+	if nil != err {
+		return Summary{}, err
+	}
+
+	c.summary = &Summary{GeneratedAt: now, Domains: domains, Top: top}
+	return *c.summary, nil
+}
@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+)
+
+// countingSource returns the number of times it was queried as the total of domains.
+type countingSource struct {
+	calls int
+	err   error
+}
+
+func (s *countingSource) CountDomains(context.Context, time.Time, time.Time) (db.DomainCounts, error) {
+	s.calls++
+	return db.DomainCounts{Total: s.calls}, s.err
+}
+
+func (s *countingSource) TopSubmitters(context.Context, int) ([]db.Submitter, error) {
+	return nil, nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	const ttl = 50 * time.Millisecond
+	source := &countingSource{}
+	c := New(source, ttl, 10)
+
+	first, err := c.Summary(ctx)
+	if nil != err || first.Domains.Total != 1 {
+		t.Fatalf("Summary() = %+v, %v, want the first computation", first, err)
+	}
+	if cached, err := c.Summary(ctx); nil != err || cached.Domains.Total != 1 || !cached.GeneratedAt.Equal(first.GeneratedAt) {
+		t.Errorf("Summary() within the TTL = %+v, %v, want the cached one", cached, err)
+	}
+
+	time.Sleep(ttl)
+	if expired, err := c.Summary(ctx); nil != err || expired.Domains.Total != 2 {
+		t.Errorf("Summary() after the TTL = %+v, %v, want a new computation", expired, err)
+	}
+
+	// Failures aren't cached, so the next call computes the summary again.
+	time.Sleep(ttl)
+	source.err = errors.New("database is locked")
+	if _, err := c.Summary(ctx); nil == err {
+		t.Error("Summary() succeeded, want the error of the source")
+	}
+	source.err = nil
+	if retried, err := c.Summary(ctx); nil != err || retried.Domains.Total != 4 {
+		t.Errorf("Summary() after a failure = %+v, %v, want a new computation", retried, err)
+	}
+}
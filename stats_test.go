@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+	"github.com/z4x7k/iran-domains-tg-bot/stats"
+)
+
+func TestStatsText(t *testing.T) {
+	catalog, err := i18n.LoadDefault()
+	if nil != err {
+		t.Fatal(err)
+	}
+	h := &Handler{log: zerolog.Nop(), catalog: catalog}
+	const named, unnamed = pseudonym.ID(0x1234567890abcdef), pseudonym.ID(0x0fedcba987654321)
+	summary := stats.Summary{
+		Domains: db.DomainCounts{Total: 30, Today: 2, ThisWeek: 5},
+		Top:     []db.Submitter{{ID: named, Name: "Sara_1", Hosts: 25}, {ID: unnamed, Hosts: 12}},
+	}
+
+	you := unnamed
+	text := h.statsText(i18n.English, summary, &you)
+	for _, want := range []string{
+		"Listed domains: 30",
+		// Public names are escaped, while submitters without one are shown by the label of their pseudonym.
+		"1\\. Sara\\_1, 25 hosts\n",
+		"2\\. `" + pseudonymLabel(unnamed) + "`, 12 hosts \\(you\\)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("statsText() = %q, want it to contain %q", text, want)
+		}
+	}
+	if label := pseudonymLabel(unnamed); label != "0fedcb" {
+		t.Errorf("pseudonymLabel() = %q, want the first 6 hex digits of the pseudonym", label)
+	}
+
+	// Users without a public name are told they're shown by their pseudonym, until they set one by /public_name.
+	if text := h.text(i18n.English, i18n.MsgStatsMine, i18n.Params{"Hosts": 12, "Name": ""}); !strings.Contains(text, "12 of the hosts") || !strings.Contains(text, "by your pseudonym") {
+		t.Errorf("stats mine without a public name = %q, want the pseudonym mentioned", text)
+	}
+	if text := h.text(i18n.English, i18n.MsgStatsMine, i18n.Params{"Hosts": 12, "Name": "Sara_1"}); !strings.Contains(text, "as Sara\\_1") {
+		t.Errorf("stats mine with a public name = %q, want the escaped name", text)
+	}
+
+	if text := h.statsText(i18n.English, stats.Summary{}, nil); strings.Contains(text, "Top contributors") {
+		t.Errorf("statsText() without submitters = %q, want no top contributors", text)
+	}
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+	"github.com/z4x7k/iran-domains-tg-bot/ratelimit"
+)
+
+// Instrumented is a Store which passes the duration of every query to observe, along with the name of its operation,
+// e.g. insert_host. The rate limiter it returns is observed as well, as ratelimit_take and ratelimit_refund.
+type Instrumented struct {
+	store   Store
+	observe func(op string, took time.Duration)
+}
+
+func NewInstrumented(store Store, observe func(op string, took time.Duration)) *Instrumented {
+	return &Instrumented{store: store, observe: observe}
+}
+
+func (s *Instrumented) done(op string, startedAt time.Time) {
+	s.observe(op, time.Since(startedAt))
+}
+
+func (s *Instrumented) FindDomain(ctx context.Context, domain string) (*model.Domains, error) {
+	defer s.done("find_domain", time.Now())
+	return s.store.FindDomain(ctx, domain)
+}
+
+func (s *Instrumented) ListDomains(ctx context.Context, before time.Time) ([]model.Domains, error) {
+	defer s.done("list_domains", time.Now())
+	return s.store.ListDomains(ctx, before)
+}
+
+func (s *Instrumented) FindHost(ctx context.Context, host string) (*model.Hosts, error) {
+	defer s.done("find_host", time.Now())
+	return s.store.FindHost(ctx, host)
+}
+
+func (s *Instrumented) InsertHost(ctx context.Context, host, domain string, submitter pseudonym.ID) (bool, error) {
+	defer s.done("insert_host", time.Now())
+	return s.store.InsertHost(ctx, host, domain, submitter)
+}
+
+func (s *Instrumented) ListHosts(ctx context.Context, before time.Time) ([]model.Hosts, error) {
+	defer s.done("list_hosts", time.Now())
+	return s.store.ListHosts(ctx, before)
+}
+
+func (s *Instrumented) ListSubmissions(ctx context.Context, submitter pseudonym.ID, offset, limit int) ([]db.Submission, int, error) {
+	defer s.done("list_submissions", time.Now())
+	return s.store.ListSubmissions(ctx, submitter, offset, limit)
+}
+
+func (s *Instrumented) CountDomains(ctx context.Context, day, week time.Time) (db.DomainCounts, error) {
+	defer s.done("count_domains", time.Now())
+	return s.store.CountDomains(ctx, day, week)
+}
+
+func (s *Instrumented) CountSubmissions(ctx context.Context, submitter pseudonym.ID) (int, error) {
+	defer s.done("count_submissions", time.Now())
+	return s.store.CountSubmissions(ctx, submitter)
+}
+
+func (s *Instrumented) TopSubmitters(ctx context.Context, limit int) ([]db.Submitter, error) {
+	defer s.done("top_submitters", time.Now())
+	return s.store.TopSubmitters(ctx, limit)
+}
+
+func (s *Instrumented) TagDomain(ctx context.Context, domain, tag string, create bool) (bool, error) {
+	defer s.done("tag_domain", time.Now())
+	return s.store.TagDomain(ctx, domain, tag, create)
+}
+
+func (s *Instrumented) TagUntaggedDomain(ctx context.Context, domain, tag string) (bool, error) {
+	defer s.done("tag_untagged_domain", time.Now())
+	return s.store.TagUntaggedDomain(ctx, domain, tag)
+}
+
+func (s *Instrumented) UntagDomain(ctx context.Context, domain, tag string) (bool, error) {
+	defer s.done("untag_domain", time.Now())
+	return s.store.UntagDomain(ctx, domain, tag)
+}
+
+func (s *Instrumented) ListTags(ctx context.Context) ([]model.Tags, error) {
+	defer s.done("list_tags", time.Now())
+	return s.store.ListTags(ctx)
+}
+
+func (s *Instrumented) DomainTags(ctx context.Context, domain string) ([]string, error) {
+	defer s.done("domain_tags", time.Now())
+	return s.store.DomainTags(ctx, domain)
+}
+
+func (s *Instrumented) ListDomainTags(ctx context.Context) (map[string][]string, error) {
+	defer s.done("list_domain_tags", time.Now())
+	return s.store.ListDomainTags(ctx)
+}
+
+func (s *Instrumented) UserLanguage(ctx context.Context, userID pseudonym.ID) (string, error) {
+	defer s.done("user_language", time.Now())
+	return s.store.UserLanguage(ctx, userID)
+}
+
+func (s *Instrumented) SetUserLanguage(ctx context.Context, userID pseudonym.ID, lang string) error {
+	defer s.done("set_user_language", time.Now())
+	return s.store.SetUserLanguage(ctx, userID, lang)
+}
+
+func (s *Instrumented) PublicName(ctx context.Context, userID pseudonym.ID) (string, error) {
+	defer s.done("public_name", time.Now())
+	return s.store.PublicName(ctx, userID)
+}
+
+func (s *Instrumented) SetPublicName(ctx context.Context, userID pseudonym.ID, name string) error {
+	defer s.done("set_public_name", time.Now())
+	return s.store.SetPublicName(ctx, userID, name)
+}
+
+func (s *Instrumented) ChatSettings(ctx context.Context, chatID pseudonym.ID) (model.Chats, error) {
+	defer s.done("chat_settings", time.Now())
+	return s.store.ChatSettings(ctx, chatID)
+}
+
+func (s *Instrumented) SetChatSettings(ctx context.Context, settings model.Chats) error {
+	defer s.done("set_chat_settings", time.Now())
+	return s.store.SetChatSettings(ctx, settings)
+}
+
+func (s *Instrumented) RateLimiter(policy ratelimit.Policy) ratelimit.Limiter {
+	return &instrumentedLimiter{limiter: s.store.RateLimiter(policy), done: s.done}
+}
+
+func (s *Instrumented) Ping(ctx context.Context) error {
+	defer s.done("ping", time.Now())
+	return s.store.Ping(ctx)
+}
+
+// instrumentedLimiter is a ratelimit.Limiter which passes the duration of every call to done.
+type instrumentedLimiter struct {
+	limiter ratelimit.Limiter
+	done    func(op string, startedAt time.Time)
+}
+
+func (l *instrumentedLimiter) Take(ctx context.Context, userID pseudonym.ID, outcomes ...ratelimit.Outcome) (ratelimit.Result, error) {
+	defer l.done("ratelimit_take", time.Now())
+	return l.limiter.Take(ctx, userID, outcomes...)
+}
+
+func (l *instrumentedLimiter) Refund(ctx context.Context, userID pseudonym.ID, outcome ratelimit.Outcome) error {
+	defer l.done("ratelimit_refund", time.Now())
+	return l.limiter.Refund(ctx, userID, outcome)
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/db/postgres"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+	"github.com/z4x7k/iran-domains-tg-bot/ratelimit"
+)
+
+// Store persists submitted domains, their hosts and tags, user preferences, group settings, and rate limiting state,
+// and computes statistics of the domains, and their submitters.
+// Implementations return db.ErrDomainNotFound, db.ErrDuplicateHost, db.ErrHostNotFound, db.ErrTagNotFound,
+// and db.ErrBusy regardless of the database.
+type Store interface {
+	FindDomain(ctx context.Context, domain string) (*model.Domains, error)
+	// ListDomains returns the domains created before the given time, ordered by name.
+	ListDomains(ctx context.Context, before time.Time) ([]model.Domains, error)
+	FindHost(ctx context.Context, host string) (*model.Hosts, error)
+	// InsertHost inserts the host, and its domain if it isn't listed yet, with the submitter recorded as the submitter of the host,
+	// and of the domain if it's inserted. It reports whether the domain was inserted.
+	InsertHost(ctx context.Context, host, domain string, submitter pseudonym.ID) (bool, error)
+	// ListHosts returns the hosts created before the given time, ordered by name.
+	ListHosts(ctx context.Context, before time.Time) ([]model.Hosts, error)
+	// ListSubmissions returns up to limit of the hosts linked to the submitter, most recent first, after skipping offset of them,
+	// with their tags and status, and the number of all hosts linked to the submitter.
+	ListSubmissions(ctx context.Context, submitter pseudonym.ID, offset, limit int) ([]db.Submission, int, error)
+	// CountDomains returns the number of all domains, and of the ones created since day, and week, in a single query.
+	CountDomains(ctx context.Context, day, week time.Time) (db.DomainCounts, error)
+	// CountSubmissions returns the number of hosts linked to the submitter.
+	CountSubmissions(ctx context.Context, submitter pseudonym.ID) (int, error)
+	// TopSubmitters returns up to limit of the submitters with the most hosts linked to them, and their public names.
+	TopSubmitters(ctx context.Context, limit int) ([]db.Submitter, error)
+	// TagDomain links the domain to the tag, and reports whether the domain wasn't tagged with it before.
+	// Unknown tags are created if create is set, otherwise db.ErrTagNotFound is returned.
+	TagDomain(ctx context.Context, domain, tag string, create bool) (bool, error)
+	// TagUntaggedDomain links the domain to the tag only if the domain has no tags, and reports whether it did,
+	// so that only one of concurrent calls tags the domain. Unknown tags return db.ErrTagNotFound.
+	TagUntaggedDomain(ctx context.Context, domain, tag string) (bool, error)
+	// UntagDomain unlinks the domain from the tag, and reports whether the domain was tagged with it.
+	UntagDomain(ctx context.Context, domain, tag string) (bool, error)
+	// ListTags returns all tags, ordered by name.
+	ListTags(ctx context.Context) ([]model.Tags, error)
+	// DomainTags returns the names of the tags of the domain, ordered by name.
+	DomainTags(ctx context.Context, domain string) ([]string, error)
+	// ListDomainTags returns the names of the tags of every tagged domain, ordered by name.
+	ListDomainTags(ctx context.Context) (map[string][]string, error)
+	// UserLanguage returns the language preferred by the user, or an empty string if they have no preference.
+	UserLanguage(ctx context.Context, userID pseudonym.ID) (string, error)
+	SetUserLanguage(ctx context.Context, userID pseudonym.ID, lang string) error
+	// PublicName returns the name the user opted in to be shown by among the top submitters, or an empty string if they didn't.
+	PublicName(ctx context.Context, userID pseudonym.ID) (string, error)
+	// SetPublicName sets the public name of the user, or deletes it if name is empty.
+	SetPublicName(ctx context.Context, userID pseudonym.ID, name string) error
+	// ChatSettings returns the settings of the group chat, which are all off if they were never changed.
+	ChatSettings(ctx context.Context, chatID pseudonym.ID) (model.Chats, error)
+	SetChatSettings(ctx context.Context, settings model.Chats) error
+	RateLimiter(policy ratelimit.Policy) ratelimit.Limiter
+	// Ping reports whether the database can be queried.
+	Ping(ctx context.Context) error
+}
+
+type SQLite struct {
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{db: db}
+}
+
+func (s *SQLite) FindDomain(ctx context.Context, domain string) (*model.Domains, error) {
+	return db.FindDomain(ctx, s.db, domain)
+}
+
+func (s *SQLite) ListDomains(ctx context.Context, before time.Time) ([]model.Domains, error) {
+	return db.ListDomains(ctx, s.db, before)
+}
+
+func (s *SQLite) FindHost(ctx context.Context, host string) (*model.Hosts, error) {
+	return db.FindHost(ctx, s.db, host)
+}
+
+func (s *SQLite) InsertHost(ctx context.Context, host, domain string, submitter pseudonym.ID) (bool, error) {
+	return db.InsertHost(ctx, s.db, host, domain, submitter)
+}
+
+func (s *SQLite) ListHosts(ctx context.Context, before time.Time) ([]model.Hosts, error) {
+	return db.ListHosts(ctx, s.db, before)
+}
+
+func (s *SQLite) ListSubmissions(ctx context.Context, submitter pseudonym.ID, offset, limit int) ([]db.Submission, int, error) {
+	return db.ListSubmissions(ctx, s.db, submitter, offset, limit)
+}
+
+func (s *SQLite) CountDomains(ctx context.Context, day, week time.Time) (db.DomainCounts, error) {
+	return db.CountDomains(ctx, s.db, day, week)
+}
+
+func (s *SQLite) CountSubmissions(ctx context.Context, submitter pseudonym.ID) (int, error) {
+	return db.CountSubmissions(ctx, s.db, submitter)
+}
+
+func (s *SQLite) TopSubmitters(ctx context.Context, limit int) ([]db.Submitter, error) {
+	return db.TopSubmitters(ctx, s.db, limit)
+}
+
+func (s *SQLite) TagDomain(ctx context.Context, domain, tag string, create bool) (bool, error) {
+	return db.TagDomain(ctx, s.db, domain, tag, create)
+}
+
+func (s *SQLite) TagUntaggedDomain(ctx context.Context, domain, tag string) (bool, error) {
+	return db.TagUntaggedDomain(ctx, s.db, domain, tag)
+}
+
+func (s *SQLite) UntagDomain(ctx context.Context, domain, tag string) (bool, error) {
+	return db.UntagDomain(ctx, s.db, domain, tag)
+}
+
+func (s *SQLite) ListTags(ctx context.Context) ([]model.Tags, error) {
+	return db.ListTags(ctx, s.db)
+}
+
+func (s *SQLite) DomainTags(ctx context.Context, domain string) ([]string, error) {
+	return db.DomainTags(ctx, s.db, domain)
+}
+
+func (s *SQLite) ListDomainTags(ctx context.Context) (map[string][]string, error) {
+	return db.ListDomainTags(ctx, s.db)
+}
+
+func (s *SQLite) UserLanguage(ctx context.Context, userID pseudonym.ID) (string, error) {
+	return db.UserLanguage(ctx, s.db, userID)
+}
+
+func (s *SQLite) SetUserLanguage(ctx context.Context, userID pseudonym.ID, lang string) error {
+	return db.SetUserLanguage(ctx, s.db, userID, lang)
+}
+
+func (s *SQLite) PublicName(ctx context.Context, userID pseudonym.ID) (string, error) {
+	return db.PublicName(ctx, s.db, userID)
+}
+
+func (s *SQLite) SetPublicName(ctx context.Context, userID pseudonym.ID, name string) error {
+	return db.SetPublicName(ctx, s.db, userID, name)
+}
+
+func (s *SQLite) ChatSettings(ctx context.Context, chatID pseudonym.ID) (model.Chats, error) {
+	return db.ChatSettings(ctx, s.db, chatID)
+}
+
+func (s *SQLite) SetChatSettings(ctx context.Context, settings model.Chats) error {
+	return db.SetChatSettings(ctx, s.db, settings)
+}
+
+func (s *SQLite) Ping(ctx context.Context) error {
+	return db.Ping(ctx, s.db)
+}
+
+func (s *SQLite) RateLimiter(policy ratelimit.Policy) ratelimit.Limiter {
+	return ratelimit.NewSQLite(s.db, policy)
+}
+
+type Postgres struct {
+	db *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (s *Postgres) FindDomain(ctx context.Context, domain string) (*model.Domains, error) {
+	return postgres.FindDomain(ctx, s.db, domain)
+}
+
+func (s *Postgres) ListDomains(ctx context.Context, before time.Time) ([]model.Domains, error) {
+	return postgres.ListDomains(ctx, s.db, before)
+}
+
+func (s *Postgres) FindHost(ctx context.Context, host string) (*model.Hosts, error) {
+	return postgres.FindHost(ctx, s.db, host)
+}
+
+func (s *Postgres) InsertHost(ctx context.Context, host, domain string, submitter pseudonym.ID) (bool, error) {
+	return postgres.InsertHost(ctx, s.db, host, domain, submitter)
+}
+
+func (s *Postgres) ListHosts(ctx context.Context, before time.Time) ([]model.Hosts, error) {
+	return postgres.ListHosts(ctx, s.db, before)
+}
+
+func (s *Postgres) ListSubmissions(ctx context.Context, submitter pseudonym.ID, offset, limit int) ([]db.Submission, int, error) {
+	return postgres.ListSubmissions(ctx, s.db, submitter, offset, limit)
+}
+
+func (s *Postgres) CountDomains(ctx context.Context, day, week time.Time) (db.DomainCounts, error) {
+	return postgres.CountDomains(ctx, s.db, day, week)
+}
+
+func (s *Postgres) CountSubmissions(ctx context.Context, submitter pseudonym.ID) (int, error) {
+	return postgres.CountSubmissions(ctx, s.db, submitter)
+}
+
+func (s *Postgres) TopSubmitters(ctx context.Context, limit int) ([]db.Submitter, error) {
+	return postgres.TopSubmitters(ctx, s.db, limit)
+}
+
+func (s *Postgres) TagDomain(ctx context.Context, domain, tag string, create bool) (bool, error) {
+	return postgres.TagDomain(ctx, s.db, domain, tag, create)
+}
+
+func (s *Postgres) TagUntaggedDomain(ctx context.Context, domain, tag string) (bool, error) {
+	return postgres.TagUntaggedDomain(ctx, s.db, domain, tag)
+}
+
+func (s *Postgres) UntagDomain(ctx context.Context, domain, tag string) (bool, error) {
+	return postgres.UntagDomain(ctx, s.db, domain, tag)
+}
+
+func (s *Postgres) ListTags(ctx context.Context) ([]model.Tags, error) {
+	return postgres.ListTags(ctx, s.db)
+}
+
+func (s *Postgres) DomainTags(ctx context.Context, domain string) ([]string, error) {
+	return postgres.DomainTags(ctx, s.db, domain)
+}
+
+func (s *Postgres) ListDomainTags(ctx context.Context) (map[string][]string, error) {
+	return postgres.ListDomainTags(ctx, s.db)
+}
+
+func (s *Postgres) UserLanguage(ctx context.Context, userID pseudonym.ID) (string, error) {
+	return postgres.UserLanguage(ctx, s.db, userID)
+}
+
+func (s *Postgres) SetUserLanguage(ctx context.Context, userID pseudonym.ID, lang string) error {
+	return postgres.SetUserLanguage(ctx, s.db, userID, lang)
+}
+
+func (s *Postgres) PublicName(ctx context.Context, userID pseudonym.ID) (string, error) {
+	return postgres.PublicName(ctx, s.db, userID)
+}
+
+func (s *Postgres) SetPublicName(ctx context.Context, userID pseudonym.ID, name string) error {
+	return postgres.SetPublicName(ctx, s.db, userID, name)
+}
+
+func (s *Postgres) ChatSettings(ctx context.Context, chatID pseudonym.ID) (model.Chats, error) {
+	return postgres.ChatSettings(ctx, s.db, chatID)
+}
+
+func (s *Postgres) SetChatSettings(ctx context.Context, settings model.Chats) error {
+	return postgres.SetChatSettings(ctx, s.db, settings)
+}
+
+func (s *Postgres) Ping(ctx context.Context) error {
+	return postgres.Ping(ctx, s.db)
+}
+
+func (s *Postgres) RateLimiter(policy ratelimit.Policy) ratelimit.Limiter {
+	return ratelimit.NewPostgres(s.db, policy)
+}
@@ -0,0 +1,281 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/db/dbtest"
+	"github.com/z4x7k/iran-domains-tg-bot/db/gen/model"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+	"github.com/z4x7k/iran-domains-tg-bot/ratelimit"
+)
+
+func TestSQLite(t *testing.T) {
+	testStore(t, NewSQLite(dbtest.SQLite(t)))
+}
+
+func TestSQLitePublishedSubmissions(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	st := NewSQLite(conn)
+	if _, err := st.InsertHost(ctx, "example.ir", "example.ir", 1); nil != err {
+		t.Fatalf("InsertHost failed: %v", err)
+	}
+	submissions, _, err := st.ListSubmissions(ctx, 1, 0, 10)
+	if nil != err || len(submissions) != 1 || nil != submissions[0].PublishedTs {
+		t.Fatalf("ListSubmissions before any publication = %+v, %v, want it unpublished", submissions, err)
+	}
+
+	// Publications list the hosts created before they were published, and the first one of them is reported.
+	created := submissions[0].CreatedTs
+	for _, publishedTs := range []int64{created, created + 20, created + 10} {
+		_, err := conn.ExecContext(ctx, "INSERT INTO publications (published_ts, domains_count, new_domains_count) VALUES (?, 1, 1)", publishedTs)
+		if nil != err {
+			t.Fatal(err)
+		}
+	}
+	submissions, _, err = st.ListSubmissions(ctx, 1, 0, 10)
+	if nil != err || len(submissions) != 1 || nil == submissions[0].PublishedTs || *submissions[0].PublishedTs != created+10 {
+		t.Fatalf("ListSubmissions after publications = %+v, %v, want it published at %d", submissions, err, created+10)
+	}
+}
+
+func TestInstrumentedRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	var ops []string
+	st := NewInstrumented(NewSQLite(dbtest.SQLite(t)), func(op string, took time.Duration) { ops = append(ops, op) })
+	limiter := st.RateLimiter(ratelimit.Policy{ratelimit.OutcomeSucceeded: {{Limit: 1, Window: time.Hour}}})
+	if res, err := limiter.Take(ctx, 1, ratelimit.OutcomeSucceeded); nil != err || !res.Allowed {
+		t.Fatalf("Take = %+v, %v, want allowed", res, err)
+	}
+	if err := limiter.Refund(ctx, 1, ratelimit.OutcomeSucceeded); nil != err {
+		t.Fatalf("Refund failed: %v", err)
+	}
+	if want := []string{"ratelimit_take", "ratelimit_refund"}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("observed ops = %v, want %v", ops, want)
+	}
+}
+
+func TestPostgres(t *testing.T) {
+	testStore(t, NewPostgres(dbtest.Postgres(t)))
+}
+
+// testStore checks the behavior every implementation of Store must share, on an empty database.
+func testStore(t *testing.T, st Store) {
+	ctx := context.Background()
+	const alice, bob = pseudonym.ID(1), pseudonym.ID(2)
+	now := time.Now().UTC()
+
+	t.Run("ping", func(t *testing.T) {
+		if err := st.Ping(ctx); nil != err {
+			t.Fatalf("Ping failed: %v", err)
+		}
+	})
+
+	t.Run("hosts", func(t *testing.T) {
+		if _, err := st.FindHost(ctx, "example.ir"); !errors.Is(err, db.ErrHostNotFound) {
+			t.Fatalf("FindHost of an unknown host = %v, want %v", err, db.ErrHostNotFound)
+		}
+		if _, err := st.FindDomain(ctx, "example.ir"); !errors.Is(err, db.ErrDomainNotFound) {
+			t.Fatalf("FindDomain of an unknown domain = %v, want %v", err, db.ErrDomainNotFound)
+		}
+
+		inserted, err := st.InsertHost(ctx, "example.ir", "example.ir", alice)
+		if nil != err || !inserted {
+			t.Fatalf("InsertHost(example.ir) = %v, %v, want true, nil", inserted, err)
+		}
+		inserted, err = st.InsertHost(ctx, "cdn.example.ir", "example.ir", bob)
+		if nil != err || inserted {
+			t.Fatalf("InsertHost(cdn.example.ir) = %v, %v, want false, nil", inserted, err)
+		}
+		if _, err := st.InsertHost(ctx, "example.ir", "example.ir", bob); !errors.Is(err, db.ErrDuplicateHost) {
+			t.Fatalf("InsertHost of a listed host = %v, want %v", err, db.ErrDuplicateHost)
+		}
+
+		host, err := st.FindHost(ctx, "cdn.example.ir")
+		if nil != err || host.Domain != "example.ir" || host.CreatedByID == nil || *host.CreatedByID != int64(bob) {
+			t.Fatalf("FindHost(cdn.example.ir) = %+v, %v, want domain example.ir created by %d", host, err, bob)
+		}
+		domain, err := st.FindDomain(ctx, "example.ir")
+		if nil != err || domain.CreatedByID == nil || *domain.CreatedByID != int64(alice) {
+			t.Fatalf("FindDomain(example.ir) = %+v, %v, want it created by %d", domain, err, alice)
+		}
+
+		later := now.Add(time.Hour)
+		domains, err := st.ListDomains(ctx, later)
+		if nil != err || len(domains) != 1 || domains[0].Domain != "example.ir" {
+			t.Fatalf("ListDomains = %+v, %v, want [example.ir]", domains, err)
+		}
+		hosts, err := st.ListHosts(ctx, later)
+		if nil != err || len(hosts) != 2 || hosts[0].Host != "cdn.example.ir" || hosts[1].Host != "example.ir" {
+			t.Fatalf("ListHosts = %+v, %v, want [cdn.example.ir example.ir]", hosts, err)
+		}
+		if hosts, err := st.ListHosts(ctx, now.Add(-time.Hour)); nil != err || len(hosts) != 0 {
+			t.Fatalf("ListHosts before the insert = %+v, %v, want none", hosts, err)
+		}
+	})
+
+	t.Run("submissions and stats", func(t *testing.T) {
+		if _, err := st.InsertHost(ctx, "shop.ir", "shop.ir", alice); nil != err {
+			t.Fatalf("InsertHost(shop.ir) failed: %v", err)
+		}
+
+		page, total, err := st.ListSubmissions(ctx, alice, 0, 1)
+		if nil != err || total != 2 || len(page) != 1 {
+			t.Fatalf("ListSubmissions(alice, 0, 1) = %+v, %d, %v, want a single host of 2", page, total, err)
+		}
+		rest, _, err := st.ListSubmissions(ctx, alice, 1, 1)
+		if nil != err || len(rest) != 1 || rest[0].Host == page[0].Host {
+			t.Fatalf("ListSubmissions(alice, 1, 1) = %+v, %v, want the other host than %s", rest, err, page[0].Host)
+		}
+		// Hosts of domains submitted by others are linked to their own submitter.
+		if hosts, total, err := st.ListSubmissions(ctx, bob, 0, 10); nil != err || total != 1 || len(hosts) != 1 || hosts[0].Host != "cdn.example.ir" {
+			t.Fatalf("ListSubmissions(bob, 0, 10) = %+v, %d, %v, want [cdn.example.ir]", hosts, total, err)
+		}
+		if n, err := st.CountSubmissions(ctx, alice); nil != err || n != 2 {
+			t.Fatalf("CountSubmissions(alice) = %d, %v, want 2", n, err)
+		}
+		if n, err := st.CountSubmissions(ctx, bob); nil != err || n != 1 {
+			t.Fatalf("CountSubmissions(bob) = %d, %v, want 1", n, err)
+		}
+
+		counts, err := st.CountDomains(ctx, now.Add(-time.Hour), now.Add(-7*24*time.Hour))
+		if want := (db.DomainCounts{Total: 2, Today: 2, ThisWeek: 2}); nil != err || counts != want {
+			t.Fatalf("CountDomains = %+v, %v, want %+v", counts, err, want)
+		}
+		counts, err = st.CountDomains(ctx, now.Add(time.Hour), now.Add(time.Hour))
+		if want := (db.DomainCounts{Total: 2}); nil != err || counts != want {
+			t.Fatalf("CountDomains since later = %+v, %v, want %+v", counts, err, want)
+		}
+
+		if err := st.SetPublicName(ctx, alice, "Alice"); nil != err {
+			t.Fatalf("SetPublicName failed: %v", err)
+		}
+		top, err := st.TopSubmitters(ctx, 10)
+		if want := []db.Submitter{{ID: alice, Name: "Alice", Hosts: 2}, {ID: bob, Hosts: 1}}; nil != err || !reflect.DeepEqual(top, want) {
+			t.Fatalf("TopSubmitters = %+v, %v, want %+v", top, err, want)
+		}
+	})
+
+	t.Run("public name", func(t *testing.T) {
+		if name, err := st.PublicName(ctx, bob); nil != err || name != "" {
+			t.Fatalf("PublicName of a user without one = %q, %v, want empty", name, err)
+		}
+		if err := st.SetPublicName(ctx, bob, "Bob"); nil != err {
+			t.Fatalf("SetPublicName failed: %v", err)
+		}
+		if err := st.SetPublicName(ctx, bob, "Robert"); nil != err {
+			t.Fatalf("SetPublicName of a user with one failed: %v", err)
+		}
+		if name, err := st.PublicName(ctx, bob); nil != err || name != "Robert" {
+			t.Fatalf("PublicName = %q, %v, want Robert", name, err)
+		}
+		if err := st.SetPublicName(ctx, bob, ""); nil != err {
+			t.Fatalf("SetPublicName of an empty name failed: %v", err)
+		}
+		if name, err := st.PublicName(ctx, bob); nil != err || name != "" {
+			t.Fatalf("PublicName of a deleted name = %q, %v, want empty", name, err)
+		}
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		// Only the tags seeded by the migrations, e.g., cdn, are known at first.
+		if _, err := st.TagDomain(ctx, "example.ir", "news", false); !errors.Is(err, db.ErrTagNotFound) {
+			t.Fatalf("TagDomain with an unknown tag = %v, want %v", err, db.ErrTagNotFound)
+		}
+		if _, err := st.TagDomain(ctx, "unknown.ir", "cdn", true); !errors.Is(err, db.ErrDomainNotFound) {
+			t.Fatalf("TagDomain of an unknown domain = %v, want %v", err, db.ErrDomainNotFound)
+		}
+		for _, tag := range []string{"news", "cdn"} {
+			if added, err := st.TagDomain(ctx, "example.ir", tag, tag == "news"); nil != err || !added {
+				t.Fatalf("TagDomain(example.ir, %s) = %v, %v, want true, nil", tag, added, err)
+			}
+		}
+		if added, err := st.TagDomain(ctx, "example.ir", "cdn", false); nil != err || added {
+			t.Fatalf("TagDomain with a linked tag = %v, %v, want false, nil", added, err)
+		}
+		if _, err := st.TagUntaggedDomain(ctx, "shop.ir", "unknown"); !errors.Is(err, db.ErrTagNotFound) {
+			t.Fatalf("TagUntaggedDomain with an unknown tag = %v, want %v", err, db.ErrTagNotFound)
+		}
+		if added, err := st.TagUntaggedDomain(ctx, "shop.ir", "cdn"); nil != err || !added {
+			t.Fatalf("TagUntaggedDomain(shop.ir, cdn) = %v, %v, want true, nil", added, err)
+		}
+		if added, err := st.TagUntaggedDomain(ctx, "shop.ir", "bank"); nil != err || added {
+			t.Fatalf("TagUntaggedDomain of a tagged domain = %v, %v, want false, nil", added, err)
+		}
+
+		tags, err := st.ListTags(ctx)
+		if nil != err {
+			t.Fatalf("ListTags failed: %v", err)
+		}
+		var names []string
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		if want := []string{"bank", "cdn", "ecommerce", "government", "news", "streaming"}; !reflect.DeepEqual(names, want) {
+			t.Fatalf("ListTags = %v, want %v", names, want)
+		}
+		if names, err := st.DomainTags(ctx, "example.ir"); nil != err || !reflect.DeepEqual(names, []string{"cdn", "news"}) {
+			t.Fatalf("DomainTags(example.ir) = %v, %v, want [cdn news]", names, err)
+		}
+		if names, err := st.DomainTags(ctx, "unknown.ir"); nil != err || len(names) != 0 {
+			t.Fatalf("DomainTags of an untagged domain = %v, %v, want none", names, err)
+		}
+
+		removed, err := st.UntagDomain(ctx, "example.ir", "news")
+		if nil != err || !removed {
+			t.Fatalf("UntagDomain(example.ir, news) = %v, %v, want true, nil", removed, err)
+		}
+		if removed, err := st.UntagDomain(ctx, "example.ir", "news"); nil != err || removed {
+			t.Fatalf("UntagDomain of an unlinked tag = %v, %v, want false, nil", removed, err)
+		}
+		all, err := st.ListDomainTags(ctx)
+		if want := map[string][]string{"example.ir": {"cdn"}, "shop.ir": {"cdn"}}; nil != err || !reflect.DeepEqual(all, want) {
+			t.Fatalf("ListDomainTags = %v, %v, want %v", all, err, want)
+		}
+
+		// Submissions carry the tags of the domains of their hosts.
+		submissions, _, err := st.ListSubmissions(ctx, bob, 0, 10)
+		if nil != err || len(submissions) != 1 || !reflect.DeepEqual(submissions[0].Tags, []string{"cdn"}) || nil != submissions[0].PublishedTs {
+			t.Fatalf("ListSubmissions(bob, 0, 10) = %+v, %v, want cdn.example.ir tagged cdn, and unpublished", submissions, err)
+		}
+	})
+
+	t.Run("user language", func(t *testing.T) {
+		if lang, err := st.UserLanguage(ctx, alice); nil != err || lang != "" {
+			t.Fatalf("UserLanguage without a preference = %q, %v, want empty", lang, err)
+		}
+		for _, lang := range []string{"fa", "en"} {
+			if err := st.SetUserLanguage(ctx, alice, lang); nil != err {
+				t.Fatalf("SetUserLanguage(%s) failed: %v", lang, err)
+			}
+			if got, err := st.UserLanguage(ctx, alice); nil != err || got != lang {
+				t.Fatalf("UserLanguage = %q, %v, want %s", got, err, lang)
+			}
+		}
+	})
+
+	t.Run("chat settings", func(t *testing.T) {
+		const chat = pseudonym.ID(-100)
+		settings, err := st.ChatSettings(ctx, chat)
+		if nil != err || settings.TheChatID != int64(chat) || settings.Silent || settings.AdminsOnly {
+			t.Fatalf("ChatSettings of a new chat = %+v, %v, want all off", settings, err)
+		}
+		for _, want := range []model.Chats{
+			{TheChatID: int64(chat), Silent: true},
+			{TheChatID: int64(chat), AdminsOnly: true},
+		} {
+			if err := st.SetChatSettings(ctx, want); nil != err {
+				t.Fatalf("SetChatSettings(%+v) failed: %v", want, err)
+			}
+			got, err := st.ChatSettings(ctx, chat)
+			if nil != err || got.Silent != want.Silent || got.AdminsOnly != want.AdminsOnly {
+				t.Fatalf("ChatSettings = %+v, %v, want %+v", got, err, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,56 @@
+// Package systemd implements the notifications of services of Type=notify to systemd, including the ones of its watchdog.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	StateReady    = "READY=1"
+	StateStopping = "STOPPING=1"
+	StateWatchdog = "WATCHDOG=1"
+)
+
+// Notify sends the state, e.g. StateReady, to systemd. It reports false, without an error, if the process isn't run
+// as a notify service, i.e. NOTIFY_SOCKET isn't set.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// Names of abstract sockets start with @, which the net package handles.
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if nil != err {
+		return false, fmt.Errorf("systemd: failed to connect to notify socket: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); nil != err {
+		return false, fmt.Errorf("systemd: failed to send notification: %v", err)
+	}
+
+	return true, nil
+}
+
+// WatchdogInterval returns the interval systemd expects StateWatchdog notifications within, i.e. WatchdogSec,
+// and whether the watchdog is enabled for the process.
+func WatchdogInterval() (time.Duration, bool, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, false, nil
+	}
+	// The watchdog is meant for another process, e.g. the parent of this one.
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if nil != err || n <= 0 {
+		return 0, false, fmt.Errorf("systemd: invalid WATCHDOG_USEC '%s'", usec)
+	}
+
+	return time.Duration(n) * time.Microsecond, true, nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+
+	"github.com/z4x7k/iran-domains-tg-bot/db"
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+	"github.com/z4x7k/iran-domains-tg-bot/markup"
+	"github.com/z4x7k/iran-domains-tg-bot/pseudonym"
+)
+
+const (
+	categoryCallbackPrefix = "tag:"
+	// maxCallbackDataLen is the limit of Telegram on inline keyboard button callback data, in bytes.
+	maxCallbackDataLen    = 64
+	maxCategoryButtons    = 12
+	categoryButtonsPerRow = 3
+)
+
+// adminIDsFromEnv returns the Telegram user identifiers permitted to run admin commands, which may be none.
+func adminIDsFromEnv() (map[int64]bool, error) {
+	admins := map[int64]bool{}
+	val, ok := os.LookupEnv(EnvKeyAdminUserIDs)
+	if !ok || val == "" {
+		return admins, nil
+	}
+	for _, part := range strings.Split(val, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if nil != err {
+			return nil, fmt.Errorf("'%s' must be a comma separated list of Telegram user identifiers: %v", EnvKeyAdminUserIDs, err)
+		}
+		admins[id] = true
+	}
+
+	return admins, nil
+}
+
+// tagCommandError is an invalid tag command, which is replied to by the message of the catalog.
+type tagCommandError struct {
+	msg    i18n.MessageID
+	params i18n.Params
+}
+
+func (e *tagCommandError) Error() string {
+	return fmt.Sprintf("invalid tag command: %s %v", e.msg, e.params)
+}
+
+// parseTagCommand parses '/tag <domain> <tag>...' and '/untag <domain> <tag>...' commands, where command is either
+// tag, or untag. The error is a *tagCommandError.
+func parseTagCommand(command, text string) (string, []string, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		return "", nil, &tagCommandError{msg: i18n.MsgTagUsage, params: i18n.Params{"Command": command}}
+	}
+	domain, err := extractDomainApexZone(fields[1])
+	if nil != err {
+		return "", nil, &tagCommandError{msg: i18n.MsgInvalidDomain}
+	}
+	tags := make([]string, 0, len(fields)-2)
+	for _, tag := range fields[2:] {
+		tag = strings.ToLower(tag)
+		if !db.ValidTagName(tag) {
+			return "", nil, &tagCommandError{msg: i18n.MsgInvalidTagName, params: i18n.Params{"Tag": tag}}
+		}
+		tags = append(tags, tag)
+	}
+
+	return domain, tags, nil
+}
+
+// replyTagCommandError replies to an invalid tag command.
+func (h *Handler) replyTagCommandError(ctx context.Context, b *bot.Bot, lang i18n.Lang, chatID int64, err error) {
+	var cmdErr *tagCommandError
+	if !errors.As(err, &cmdErr) {
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	h.replyText(ctx, b, chatID, h.text(lang, cmdErr.msg, cmdErr.params), markup.MarkdownV2.ParseMode())
+}
+
+// checkAdmin replies to the user and returns false if they aren't permitted to run admin commands.
+func (h *Handler) checkAdmin(ctx context.Context, b *bot.Bot, lang i18n.Lang, update *models.Update) bool {
+	if h.admins[update.Message.From.ID] {
+		return true
+	}
+	h.replyText(ctx, b, update.Message.Chat.ID, h.text(lang, i18n.MsgAdminOnly, nil), markup.MarkdownV2.ParseMode())
+	return false
+}
+
+func (h *Handler) handleTagCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscard(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
+	if !h.checkAdmin(ctx, b, lang, update) {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	domain, tags, err := parseTagCommand("tag", update.Message.Text)
+	if nil != err {
+		h.replyTagCommandError(ctx, b, lang, chatID, err)
+		return
+	}
+	var added []string
+	for _, tag := range tags {
+		ok, err := h.store.TagDomain(ctx, domain, tag, true)
+		if nil != err {
+			if errors.Is(err, db.ErrDomainNotFound) {
+				h.replyText(ctx, b, chatID, h.text(lang, i18n.MsgDomainNotListed, i18n.Params{"Domain": domain}), markup.MarkdownV2.ParseMode())
+				return
+			}
+			log.Error().Err(err).Str("domain", domain).Str("tag", tag).Msg("failed to tag domain")
+			h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+			return
+		}
+		if ok {
+			added = append(added, tag)
+		}
+	}
+	log.Info().Str("domain", domain).Strs("tags", added).Msg("tagged domain")
+
+	h.replyDomainTags(ctx, b, log, lang, chatID, domain)
+}
+
+func (h *Handler) handleUntagCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscard(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
+	if !h.checkAdmin(ctx, b, lang, update) {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	domain, tags, err := parseTagCommand("untag", update.Message.Text)
+	if nil != err {
+		h.replyTagCommandError(ctx, b, lang, chatID, err)
+		return
+	}
+	var removed []string
+	for _, tag := range tags {
+		ok, err := h.store.UntagDomain(ctx, domain, tag)
+		if nil != err {
+			log.Error().Err(err).Str("domain", domain).Str("tag", tag).Msg("failed to untag domain")
+			h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+			return
+		}
+		if ok {
+			removed = append(removed, tag)
+		}
+	}
+	log.Info().Str("domain", domain).Strs("tags", removed).Msg("untagged domain")
+
+	h.replyDomainTags(ctx, b, log, lang, chatID, domain)
+}
+
+func (h *Handler) handleTagsCommand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscard(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	lang := h.userLanguage(ctx, log, update.Message.From)
+	if !h.checkAdmin(ctx, b, lang, update) {
+		return
+	}
+	chatID := update.Message.Chat.ID
+
+	tags, err := h.store.ListTags(ctx)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to list tags")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	domainTags, err := h.store.ListDomainTags(ctx)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to list domain tags")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	counts := map[string]int{}
+	for _, names := range domainTags {
+		for _, name := range names {
+			counts[name]++
+		}
+	}
+
+	rows := make([]i18n.Params, 0, len(tags))
+	for _, t := range tags {
+		rows = append(rows, i18n.Params{"Name": t.Name, "Domains": counts[t.Name]})
+	}
+	params := i18n.Params{"Total": len(tags), "Tagged": len(domainTags), "Tags": rows}
+	h.replyText(ctx, b, chatID, h.text(lang, i18n.MsgTagsList, params), markup.MarkdownV2.ParseMode())
+}
+
+func (h *Handler) replyDomainTags(ctx context.Context, b *bot.Bot, log zerolog.Logger, lang i18n.Lang, chatID int64, domain string) {
+	tags, err := h.store.DomainTags(ctx, domain)
+	if nil != err {
+		log.Error().Err(err).Str("domain", domain).Msg("failed to list domain tags")
+		h.replyInternalError(ctx, b, lang, reply{chatID: chatID})
+		return
+	}
+	text := h.text(lang, i18n.MsgDomainTags, i18n.Params{"Domain": domain, "Tags": tags})
+	h.replyText(ctx, b, chatID, text, markup.MarkdownV2.ParseMode())
+}
+
+// replyText sends text to the chat, which is plain if parseMode is empty, e.g. when it includes user input.
+func (h *Handler) replyText(ctx context.Context, b *bot.Bot, chatID int64, text string, parseMode models.ParseMode) {
+	msg := bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: parseMode,
+	}
+	if _, sendErr := h.sender.SendMessage(ctx, b, &msg); nil != sendErr {
+		h.log.
+			Error().
+			Err(sendErr).
+			Dict("reply_message", h.replyDict(chatID)).
+			Msg("failed to send reply message to user chat")
+	}
+}
+
+// categoryKeyboard returns the inline keyboard the submitter may pick a category of the domain from,
+// or nil if there are no tags, or a button wouldn't fit in the callback data limit.
+func (h *Handler) categoryKeyboard(ctx context.Context, log zerolog.Logger, domain string) *models.InlineKeyboardMarkup {
+	tags, err := h.store.ListTags(ctx)
+	if nil != err {
+		log.Error().Err(err).Msg("failed to list tags for category keyboard")
+		return nil
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if len(tags) > maxCategoryButtons {
+		tags = tags[:maxCategoryButtons]
+	}
+
+	var rows [][]models.InlineKeyboardButton
+	for i, t := range tags {
+		data := categoryCallbackPrefix + t.Name + ":" + domain
+		if len(data) > maxCallbackDataLen {
+			return nil
+		}
+		if i%categoryButtonsPerRow == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], models.InlineKeyboardButton{Text: t.Name, CallbackData: data})
+	}
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// handleCategoryCallback tags a domain with the category picked by its submitter. Only the first pick counts,
+// even if picks race, after which the keyboard is removed. Later changes are made by admins.
+func (h *Handler) handleCategoryCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if shouldDiscardCallback(update) {
+		return
+	}
+	log := h.loggerFromUpdate(update)
+	query := update.CallbackQuery
+	lang := h.userLanguage(ctx, log, &query.Sender)
+
+	tag, domain, ok := strings.Cut(strings.TrimPrefix(query.Data, categoryCallbackPrefix), ":")
+	if !ok {
+		h.answerCallback(ctx, b, log, query, "")
+		return
+	}
+	log = log.With().Str("domain", domain).Str("tag", tag).Logger()
+
+	d, err := h.store.FindDomain(ctx, domain)
+	if nil != err {
+		if errors.Is(err, db.ErrDomainNotFound) {
+			h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgCategoryDomainNotFound, nil))
+			h.removeInlineKeyboard(ctx, b, log, query.Message)
+			return
+		}
+		log.Error().Err(err).Msg("failed to lookup domain from database")
+		h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgInternalError, nil))
+		return
+	}
+	if nil == d.CreatedByID || pseudonym.ID(*d.CreatedByID) != h.pseudonymizer.ID(query.Sender.ID) {
+		h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgCategoryOnlySubmitter, nil))
+		h.removeInlineKeyboard(ctx, b, log, query.Message)
+		return
+	}
+	added, err := h.store.TagUntaggedDomain(ctx, domain, tag)
+	if nil != err {
+		switch {
+		case errors.Is(err, db.ErrTagNotFound):
+			h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgCategoryNotFound, nil))
+		case errors.Is(err, db.ErrDomainNotFound):
+			h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgCategoryDomainNotFound, nil))
+			h.removeInlineKeyboard(ctx, b, log, query.Message)
+		default:
+			log.Error().Err(err).Msg("failed to tag domain with submitter category")
+			h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgInternalError, nil))
+		}
+		return
+	}
+	if !added {
+		h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgCategoryAlreadySet, nil))
+		h.removeInlineKeyboard(ctx, b, log, query.Message)
+		return
+	}
+	log.Info().Msg("tagged domain with submitter category")
+
+	h.answerCallback(ctx, b, log, query, h.plainText(lang, i18n.MsgCategorySet, i18n.Params{"Tag": tag}))
+	h.removeInlineKeyboard(ctx, b, log, query.Message)
+}
+
+func (h *Handler) answerCallback(ctx context.Context, b *bot.Bot, log zerolog.Logger, query *models.CallbackQuery, text string) {
+	if _, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: query.ID, Text: text}); nil != err {
+		log.Error().Err(err).Msg("failed to answer callback query")
+	}
+}
+
+func (h *Handler) removeInlineKeyboard(ctx context.Context, b *bot.Bot, log zerolog.Logger, msg *models.Message) {
+	err := h.sender.Do(ctx, msg.Chat.ID, func(ctx context.Context) error {
+		_, err := b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:      msg.Chat.ID,
+			MessageID:   msg.ID,
+			ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{}},
+		})
+		return err
+	})
+	if nil != err {
+		log.Error().Err(err).Msg("failed to remove inline keyboard")
+	}
+}
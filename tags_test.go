@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/z4x7k/iran-domains-tg-bot/i18n"
+)
+
+func TestParseTagCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantDomain string
+		wantTags   []string
+		wantErr    *tagCommandError
+	}{
+		{name: "tags", text: "/tag cdn.git.ir CDN news", wantDomain: "git.ir", wantTags: []string{"cdn", "news"}},
+		{name: "bare command", text: "/untag", wantErr: &tagCommandError{msg: i18n.MsgTagUsage, params: i18n.Params{"Command": "untag"}}},
+		{name: "missing tags", text: "/untag git.ir", wantErr: &tagCommandError{msg: i18n.MsgTagUsage, params: i18n.Params{"Command": "untag"}}},
+		{name: "invalid domain", text: "/untag git news", wantErr: &tagCommandError{msg: i18n.MsgInvalidDomain}},
+		{name: "invalid tag", text: "/untag git.ir e_commerce", wantErr: &tagCommandError{msg: i18n.MsgInvalidTagName, params: i18n.Params{"Tag": "e_commerce"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, tags, err := parseTagCommand("untag", tt.text)
+			if nil != tt.wantErr {
+				var got *tagCommandError
+				if !errors.As(err, &got) || !reflect.DeepEqual(got, tt.wantErr) {
+					t.Fatalf("parseTagCommand(%q) = %v, want %v", tt.text, err, tt.wantErr)
+				}
+				return
+			}
+			if nil != err || domain != tt.wantDomain || !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Fatalf("parseTagCommand(%q) = %q, %v, %v, want %q, %v", tt.text, domain, tags, err, tt.wantDomain, tt.wantTags)
+			}
+		})
+	}
+}